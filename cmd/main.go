@@ -4,21 +4,30 @@ package main
 // 	- Add return/handle errors in places where it makes sense.
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	logger "github.com/ride90/game-of-life"
 	"github.com/ride90/game-of-life/configs"
 	"github.com/ride90/game-of-life/handlers"
+	"github.com/ride90/game-of-life/internal/multiverse"
+	"github.com/ride90/game-of-life/internal/search"
 	"github.com/ride90/game-of-life/internal/ws"
 	"github.com/ride90/game-of-life/middlewares"
 	"github.com/ride90/game-of-life/tasks"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 )
 
 var cfg *configs.Config
 var wsHub *ws.Hub
+var soupSearch *search.Search
 
 func init() {
 	// Config.
@@ -30,11 +39,36 @@ func init() {
 
 	// Setup a logger.
 	logger.SetupLogger(cfg)
+
+	// Limit the size of the multiverse.
+	multiverse.GetInstance().SetLimits(cfg.Game.MaxUniverses, cfg.Game.MaxTotalCells)
+	multiverse.GetInstance().SetHistoryDepth(cfg.Game.HistoryDepth)
+	// Tick rate, it can be changed at runtime.
+	if err := multiverse.GetInstance().UpdateSimulation(multiverse.SimulationPatch{Fps: &cfg.Game.Fps}); err != nil {
+		log.Fatal("Simulation: ", err)
+	}
+
+	// Results of soup searches, also of previous runs.
+	var err error
+	if soupSearch, err = search.New(cfg.Search.ResultsFile, cfg.Search.RareFrequency); err != nil {
+		log.Fatal("Soup search: ", err)
+	}
 }
 
 func main() {
 	// Evolve universes & stream updates via ws to clients.
 	go tasks.StreamUpdates(wsHub, cfg)
+	// Search soups for rare objects in free multiverse slots, until the server stops.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var background sync.WaitGroup
+	if cfg.Search.Enabled {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			tasks.SearchSoups(ctx, soupSearch, cfg)
+		}()
+	}
 
 	router := mux.NewRouter()
 	// Global middlewares.
@@ -48,8 +82,26 @@ func main() {
 	apiHandler := handlers.NewHandlerAPI(cfg)
 	routerAPI.HandleFunc("/health", apiHandler.Health).Methods(http.MethodGet)
 	routerAPI.HandleFunc("/universe", apiHandler.CreateUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/universe/random", apiHandler.RandomUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}", apiHandler.GetUniverse).Methods(http.MethodGet)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}", apiHandler.UpdateUniverse).Methods(http.MethodPatch)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}", apiHandler.DeleteUniverse).Methods(http.MethodDelete)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/split", apiHandler.SplitUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/census", apiHandler.UniverseCensus).Methods(http.MethodGet)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/export", apiHandler.ExportUniverse).Methods(http.MethodGet)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/pause", apiHandler.PauseUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/resume", apiHandler.ResumeUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/step", apiHandler.StepUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/universe/{id:[0-9]+}/rewind", apiHandler.RewindUniverse).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/simulation", apiHandler.GetSimulation).Methods(http.MethodGet)
+	routerAPI.HandleFunc("/simulation", apiHandler.UpdateSimulation).Methods(http.MethodPatch)
+	routerAPI.HandleFunc("/simulation/pause", apiHandler.PauseSimulation).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/simulation/resume", apiHandler.ResumeSimulation).Methods(http.MethodPost)
+	routerAPI.HandleFunc("/simulation/step", apiHandler.StepSimulation).Methods(http.MethodPost)
 	routerAPI.HandleFunc("/bigbang", apiHandler.ResetMultiverse).Methods(http.MethodPost)
 	routerAPI.HandleFunc("/merge", apiHandler.MergeUniverses).Methods(http.MethodPost)
+	searchHandler := handlers.NewHandlerSearch(soupSearch)
+	routerAPI.HandleFunc("/search", searchHandler.Results).Methods(http.MethodGet)
 
 	// WS handler.
 	wsHandler := handlers.NewHandlerWS(cfg)
@@ -74,5 +126,19 @@ func main() {
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 	}
 	log.Info("Running server on ", addr)
-	log.Fatal(srv.ListenAndServe())
+	go func() {
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stop gracefully, so results of the soup search are persisted.
+	<-ctx.Done()
+	log.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("Shutdown: ", err)
+	}
+	background.Wait()
 }
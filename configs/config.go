@@ -24,8 +24,26 @@ type Config struct {
 		Fps                       int  `yaml:"fps" envconfig:"GAME_FPS"`
 		UniversePrepend           bool `yaml:"universe_prepend" envconfig:"GAME_UNIVERSE_PREPEND"`
 		RemoveStaticUniverseAfter int  `yaml:"remove_static_universe_after" envconfig:"GAME_REMOVE_STATIC_UNIVERSE_AFTER"`
+		MaxPeriod                 int  `yaml:"max_period" envconfig:"GAME_MAX_PERIOD"`
+		MaxUniverses              int  `yaml:"max_universes" envconfig:"GAME_MAX_UNIVERSES"`
+		MaxTotalCells             int  `yaml:"max_total_cells" envconfig:"GAME_MAX_TOTAL_CELLS"`
+		HistoryDepth              int  `yaml:"history_depth" envconfig:"GAME_HISTORY_DEPTH"`
 	} `yaml:"game"`
 
+	Search struct {
+		Enabled        bool    `yaml:"enabled" envconfig:"SEARCH_ENABLED"`
+		Soups          int     `yaml:"soups" envconfig:"SEARCH_SOUPS"`
+		Width          int     `yaml:"width" envconfig:"SEARCH_WIDTH"`
+		Height         int     `yaml:"height" envconfig:"SEARCH_HEIGHT"`
+		Density        float64 `yaml:"density" envconfig:"SEARCH_DENSITY"`
+		Symmetry       string  `yaml:"symmetry" envconfig:"SEARCH_SYMMETRY"`
+		Rule           string  `yaml:"rule" envconfig:"SEARCH_RULE"`
+		Topology       string  `yaml:"topology" envconfig:"SEARCH_TOPOLOGY"`
+		MaxGenerations int     `yaml:"max_generations" envconfig:"SEARCH_MAX_GENERATIONS"`
+		RareFrequency  float64 `yaml:"rare_frequency" envconfig:"SEARCH_RARE_FREQUENCY"`
+		ResultsFile    string  `yaml:"results_file" envconfig:"SEARCH_RESULTS_FILE"`
+	} `yaml:"search"`
+
 	Log struct {
 		Level           string `yaml:"level" envconfig:"LOG_LEVEL"`
 		SetReportCaller bool   `yaml:"set_report_caller" envconfig:"LOG_SET_REPORT_CALLER"`
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/ride90/game-of-life/configs"
 	"github.com/ride90/game-of-life/internal/multiverse"
+	"github.com/ride90/game-of-life/internal/pattern"
 	"github.com/ride90/game-of-life/internal/universe"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"net/http"
+	"strconv"
 )
 
 // HandlerAPI API requests handler
@@ -33,33 +39,174 @@ func (h HandlerAPI) CreateUniverse(w http.ResponseWriter, r *http.Request) {
 	mv := multiverse.GetInstance()
 	if mv.IsFull() {
 		log.Warn("Not possible to create universe. Multiverse is full.")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode("Multiverse is full")
+		writeError(w, http.StatusConflict, multiverse.ErrMultiverseFull)
 		return
 	}
 
-	// Decode from stream into Universe struct instance.
-	var u universe.Universe
-	err := json.NewDecoder(r.Body).Decode(&u)
+	// Decode a universe of the kind given in the body, Life by default.
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Error(err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	u, err := universe.Decode(body, h.config.Game.MaxPeriod)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.addUniverse(w, mv, u, body)
+}
+
+// RandomUniverse handles the creation of a universe from a seeded random soup
+func (h HandlerAPI) RandomUniverse(w http.ResponseWriter, r *http.Request) {
+	mv := multiverse.GetInstance()
+	if mv.IsFull() {
+		log.Warn("Not possible to create universe. Multiverse is full.")
+		writeError(w, http.StatusConflict, multiverse.ErrMultiverseFull)
+		return
+	}
 
-	// Calculate initial universe stats.
-	u.UpdateStats()
-	log.Infoln("Created new universe", &u)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	u, err := universe.DecodeSoup(body, h.config.Game.MaxPeriod)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.addUniverse(w, mv, u, body)
+}
+
+// universeSpeed holds the optional speed of a universe next to its other fields
+type universeSpeed struct {
+	GenerationsPerTick *int `json:"generations_per_tick"`
+}
+
+// addUniverse adds a new universe into the multiverse at the speed given in the body & writes its ID
+func (h HandlerAPI) addUniverse(w http.ResponseWriter, mv *multiverse.Multiverse, u universe.Automaton, body []byte) {
+	var speed universeSpeed
+	if err := json.Unmarshal(body, &speed); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if speed.GenerationsPerTick != nil {
+		if err := multiverse.CheckGenerationsPerTick(*speed.GenerationsPerTick); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	log.Infoln("Created new universe", u)
 
 	// Add universe into multiverse.
+	var id uint64
+	var err error
 	if h.config.Game.UniversePrepend {
-		mv.PrependUniverse(&u)
+		id, err = mv.PrependUniverse(u)
 	} else {
-		mv.AppendUniverse(&u)
+		id, err = mv.AppendUniverse(u)
+	}
+	if err != nil {
+		log.Warn("Not possible to create universe. ", err)
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if speed.GenerationsPerTick != nil {
+		if err = mv.SetGenerationsPerTick(id, *speed.GenerationsPerTick); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
 	}
 
-	// Write response status.
+	// Write response status & ID of the new universe.
+	w.Header().Set("Location", fmt.Sprintf("/api/universe/%d", id))
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		ID uint64 `json:"id"`
+	}{id})
+}
+
+// GetUniverse handles the retrieval of a universe
+func (h HandlerAPI) GetUniverse(w http.ResponseWriter, r *http.Request) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	data, err := mv.UniverseJSON(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.Write(data)
+}
+
+// UpdateUniverse handles changes of a universe's colour, rule, topology, engine, step or speed
+// Only the speed of universes other than Life ones can be changed.
+func (h HandlerAPI) UpdateUniverse(w http.ResponseWriter, r *http.Request) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var patch struct {
+		universe.Patch
+		universeSpeed
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if patch.GenerationsPerTick != nil {
+		if err = multiverse.CheckGenerationsPerTick(*patch.GenerationsPerTick); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	mv := multiverse.GetInstance()
+	if patch.Patch != (universe.Patch{}) {
+		if err = mv.UpdateUniverse(id, patch.Patch); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+	}
+	if patch.GenerationsPerTick != nil {
+		if err = mv.SetGenerationsPerTick(id, *patch.GenerationsPerTick); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+	}
+	data, err := mv.UniverseJSON(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.Write(data)
+}
+
+// DeleteUniverse handles the removal of a universe
+func (h HandlerAPI) DeleteUniverse(w http.ResponseWriter, r *http.Request) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	if err = mv.RemoveUniverse(id); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // ResetMultiverse handles the resetting of the multiverse
@@ -72,12 +219,285 @@ func (h HandlerAPI) ResetMultiverse(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// MergeUniverses handles the merging of all universes together
+// SplitUniverse handles the split of a universe into a grid of tiles or connected components
+func (h HandlerAPI) SplitUniverse(w http.ResponseWriter, r *http.Request) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var opts universe.SplitOptions
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	ids, err := mv.Split(id, opts, h.config.Game.UniversePrepend)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	// Write response status & IDs of new universes.
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		IDs []uint64 `json:"ids"`
+	}{ids})
+}
+
+// MergeUniverses handles the merging of universes together
+// An optional body selects universes, all by default, the layout & the gap between them.
 func (h HandlerAPI) MergeUniverses(w http.ResponseWriter, r *http.Request) {
+	var opts multiverse.MergeOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
 
 	mv := multiverse.GetInstance()
-	mv.Merge()
+	id, err := mv.Merge(opts)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
 
-	// Write response status.
+	// Write response status & ID of the merged universe, if there was a merge.
 	w.WriteHeader(http.StatusOK)
+	if id != 0 {
+		json.NewEncoder(w).Encode(struct {
+			ID uint64 `json:"id"`
+		}{id})
+	}
+}
+
+// UniverseCensus handles the census of objects of a universe
+func (h HandlerAPI) UniverseCensus(w http.ResponseWriter, r *http.Request) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	census, err := mv.Census(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		Summary string          `json:"summary"`
+		Objects universe.Census `json:"objects"`
+	}{census.String(), census})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// ExportUniverse handles the export of a universe as a pattern file
+// The format is given by the "format" query parameter, RLE by default. Every format
+// is two-state, dying cells & colours of cells aren't exported.
+func (h HandlerAPI) ExportUniverse(w http.ResponseWriter, r *http.Request) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "rle"
+	}
+	format, err := pattern.ParseFormat(formatName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	p, err := mv.Pattern(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"universe-%d.%s\"", id, format.Extension),
+	)
+	if _, err = io.WriteString(w, format.Encode(p)); err != nil {
+		log.Error(err)
+	}
+}
+
+// GetSimulation handles the retrieval of the state of the simulation
+func (h HandlerAPI) GetSimulation(w http.ResponseWriter, r *http.Request) {
+	writeSimulation(w, multiverse.GetInstance())
+}
+
+// UpdateSimulation handles changes of the tick rate or pausing of the simulation
+func (h HandlerAPI) UpdateSimulation(w http.ResponseWriter, r *http.Request) {
+	var patch multiverse.SimulationPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	if err := mv.UpdateSimulation(patch); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeSimulation(w, mv)
+}
+
+// PauseSimulation handles freezing of all universes
+func (h HandlerAPI) PauseSimulation(w http.ResponseWriter, r *http.Request) {
+	mv := multiverse.GetInstance()
+	mv.Pause()
+	writeSimulation(w, mv)
+}
+
+// ResumeSimulation handles resuming of the evolution of all universes
+func (h HandlerAPI) ResumeSimulation(w http.ResponseWriter, r *http.Request) {
+	mv := multiverse.GetInstance()
+	mv.Resume()
+	writeSimulation(w, mv)
+}
+
+// StepSimulation handles advancing universes by the number of ticks given by the "n" query parameter, 1 by default
+func (h HandlerAPI) StepSimulation(w http.ResponseWriter, r *http.Request) {
+	n, err := steps(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	if err = mv.Step(n); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeSimulation(w, mv)
+}
+
+// PauseUniverse handles freezing of a universe
+func (h HandlerAPI) PauseUniverse(w http.ResponseWriter, r *http.Request) {
+	h.controlUniverse(w, r, func(mv *multiverse.Multiverse, id uint64) error {
+		return mv.PauseUniverse(id)
+	})
+}
+
+// ResumeUniverse handles resuming of the evolution of a universe
+func (h HandlerAPI) ResumeUniverse(w http.ResponseWriter, r *http.Request) {
+	h.controlUniverse(w, r, func(mv *multiverse.Multiverse, id uint64) error {
+		return mv.ResumeUniverse(id)
+	})
+}
+
+// StepUniverse handles advancing a universe by the number of ticks given by the "n" query parameter, 1 by default
+func (h HandlerAPI) StepUniverse(w http.ResponseWriter, r *http.Request) {
+	n, err := steps(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.controlUniverse(w, r, func(mv *multiverse.Multiverse, id uint64) error {
+		return mv.StepUniverse(id, n)
+	})
+}
+
+// RewindUniverse handles turning a universe back by ?generations=N generations, 1 by default
+func (h HandlerAPI) RewindUniverse(w http.ResponseWriter, r *http.Request) {
+	generations := 1
+	if query := r.URL.Query().Get("generations"); query != "" {
+		var err error
+		if generations, err = strconv.Atoi(query); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if generations < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("generations must be positive, got %d", generations))
+			return
+		}
+	}
+	h.controlUniverse(w, r, func(mv *multiverse.Multiverse, id uint64) error {
+		return mv.RewindUniverse(id, generations)
+	})
+}
+
+// controlUniverse applies a control to a universe & writes the universe
+func (h HandlerAPI) controlUniverse(
+	w http.ResponseWriter, r *http.Request, control func(mv *multiverse.Multiverse, id uint64) error,
+) {
+	id, err := universeID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	mv := multiverse.GetInstance()
+	if err = control(mv, id); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	data, err := mv.UniverseJSON(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.Write(data)
+}
+
+// writeSimulation writes the state of the simulation
+func writeSimulation(w http.ResponseWriter, mv *multiverse.Multiverse) {
+	err := json.NewEncoder(w).Encode(mv.Simulation())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// steps parses the optional "n" query parameter of a step
+func steps(r *http.Request) (int, error) {
+	query := r.URL.Query().Get("n")
+	if query == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(query)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n > multiverse.MaxSteps {
+		return 0, fmt.Errorf("n must be between 1 and %d, got %d", multiverse.MaxSteps, n)
+	}
+	return n, nil
+}
+
+// universeID parses the universe ID path variable
+func universeID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+}
+
+// statusOf maps multiverse errors to HTTP status codes
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, multiverse.ErrUniverseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, multiverse.ErrMultiverseFull):
+		return http.StatusConflict
+	default:
+		// Including ErrCellBudgetExceeded, ErrUnsupportedKind & ErrRuleMismatch.
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// writeError writes an error message as a JSON string
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(err.Error())
 }
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/ride90/game-of-life/configs"
+	"github.com/ride90/game-of-life/internal/multiverse"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newRouter routes API requests to handlers the way the server does
+// The multiverse is reset & its limits lifted, every test starts empty.
+func newRouter(t *testing.T) (*mux.Router, *configs.Config) {
+	t.Helper()
+	mv := multiverse.GetInstance()
+	mv.Reset()
+	mv.SetLimits(0, 0)
+	t.Cleanup(func() {
+		mv.Reset()
+		fps := multiverse.DefaultFps
+		mv.UpdateSimulation(multiverse.SimulationPatch{Fps: &fps})
+		mv.Resume()
+	})
+
+	cfg := &configs.Config{}
+	cfg.Game.MaxPeriod = 10
+	h := NewHandlerAPI(cfg)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/universe", h.CreateUniverse).Methods(http.MethodPost)
+	router.HandleFunc("/api/universe/random", h.RandomUniverse).Methods(http.MethodPost)
+	router.HandleFunc("/api/universe/{id:[0-9]+}", h.GetUniverse).Methods(http.MethodGet)
+	router.HandleFunc("/api/universe/{id:[0-9]+}", h.UpdateUniverse).Methods(http.MethodPatch)
+	router.HandleFunc("/api/universe/{id:[0-9]+}", h.DeleteUniverse).Methods(http.MethodDelete)
+	router.HandleFunc("/api/universe/{id:[0-9]+}/split", h.SplitUniverse).Methods(http.MethodPost)
+	router.HandleFunc("/api/universe/{id:[0-9]+}/census", h.UniverseCensus).Methods(http.MethodGet)
+	router.HandleFunc("/api/universe/{id:[0-9]+}/export", h.ExportUniverse).Methods(http.MethodGet)
+	router.HandleFunc("/api/universe/{id:[0-9]+}/pause", h.PauseUniverse).Methods(http.MethodPost)
+	router.HandleFunc("/api/universe/{id:[0-9]+}/resume", h.ResumeUniverse).Methods(http.MethodPost)
+	router.HandleFunc("/api/universe/{id:[0-9]+}/step", h.StepUniverse).Methods(http.MethodPost)
+	router.HandleFunc("/api/simulation", h.GetSimulation).Methods(http.MethodGet)
+	router.HandleFunc("/api/simulation", h.UpdateSimulation).Methods(http.MethodPatch)
+	router.HandleFunc("/api/simulation/pause", h.PauseSimulation).Methods(http.MethodPost)
+	router.HandleFunc("/api/simulation/resume", h.ResumeSimulation).Methods(http.MethodPost)
+	router.HandleFunc("/api/simulation/step", h.StepSimulation).Methods(http.MethodPost)
+	router.HandleFunc("/api/merge", h.MergeUniverses).Methods(http.MethodPost)
+	return router, cfg
+}
+
+// serve sends a request to the router & returns the response
+func serve(router *mux.Router, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+// create creates a universe & returns its ID
+func create(t *testing.T, router *mux.Router, body string) uint64 {
+	t.Helper()
+	w := serve(router, http.MethodPost, "/api/universe", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating %s: status %d, body %s", body, w.Code, w.Body)
+	}
+	var created struct {
+		ID uint64 `json:"id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	return created.ID
+}
+
+const blinker = `{"cells": [[false, false, false], [true, true, true], [false, false, false]]}`
+
+func TestUniverseLifecycle(t *testing.T) {
+	router, _ := newRouter(t)
+	id := create(t, router, blinker)
+
+	w := serve(router, http.MethodGet, "/api/universe/"+itoa(id), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: status %d, body %s", w.Code, w.Body)
+	}
+	var got struct {
+		ID    uint64 `json:"id"`
+		Alive int    `json:"alive"`
+		Rule  string `json:"rule"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != id || got.Alive != 3 || got.Rule != "B3/S23" {
+		t.Errorf("got universe %+v", got)
+	}
+
+	w = serve(router, http.MethodPatch, "/api/universe/"+itoa(id), `{"rule": "highlife"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"B36/S23"`) {
+		t.Errorf("patch: status %d, body %s", w.Code, w.Body)
+	}
+
+	if w = serve(router, http.MethodDelete, "/api/universe/"+itoa(id), ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d, body %s", w.Code, w.Body)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete} {
+		if w = serve(router, method, "/api/universe/"+itoa(id), `{"colour": "#fff"}`); w.Code != http.StatusNotFound {
+			t.Errorf("%s of a deleted universe: status %d, want 404", method, w.Code)
+		}
+	}
+}
+
+func TestUniverseErrors(t *testing.T) {
+	router, _ := newRouter(t)
+	wireworld := "/api/universe/" + itoa(create(t, router, `{"kind": "wireworld", "states": [[1, 2, 3, 3]]}`))
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"invalid json", http.MethodPost, "/api/universe", `{"cells": `, http.StatusBadRequest},
+		{"unknown rule", http.MethodPost, "/api/universe", `{"cells": [[true]], "rule": "B9"}`, http.StatusBadRequest},
+		{"unknown kind", http.MethodPost, "/api/universe", `{"kind": "chess"}`, http.StatusBadRequest},
+		{"invalid speed", http.MethodPost, "/api/universe", `{"cells": [[true]], "generations_per_tick": 0}`, http.StatusBadRequest},
+		{"oversized soup", http.MethodPost, "/api/universe/random", `{"width": 9223372036854775807, "height": 2}`, http.StatusBadRequest},
+		{"unknown field", http.MethodPatch, wireworld, `{"size": 3}`, http.StatusBadRequest},
+		{"missing universe", http.MethodGet, "/api/universe/404", "", http.StatusNotFound},
+		{"missing export", http.MethodGet, "/api/universe/404/export", "", http.StatusNotFound},
+		{"unknown format", http.MethodGet, wireworld + "/export?format=gif", "", http.StatusBadRequest},
+		{"wireworld rule", http.MethodPatch, wireworld, `{"rule": "B3/S23"}`, http.StatusUnprocessableEntity},
+		{"wireworld census", http.MethodGet, wireworld + "/census", "", http.StatusUnprocessableEntity},
+		{"wireworld export", http.MethodGet, wireworld + "/export", "", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		if w := serve(router, tt.method, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d, body %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestUniverseLimits(t *testing.T) {
+	router, _ := newRouter(t)
+	multiverse.GetInstance().SetLimits(2, 20)
+	create(t, router, blinker)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"cell budget", "/api/universe", `{"width": 5, "height": 5, "rle": "x = 1, y = 1\no!"}`, http.StatusUnprocessableEntity},
+		{"random cell budget", "/api/universe/random", `{"width": 4, "height": 4, "seed": 1}`, http.StatusUnprocessableEntity},
+		{"fits", "/api/universe", blinker, http.StatusCreated},
+		{"full", "/api/universe", `{"cells": [[true]]}`, http.StatusConflict},
+		{"random full", "/api/universe/random", `{"width": 1, "height": 1, "seed": 1}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if w := serve(router, http.MethodPost, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d, body %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestPauseAndStep(t *testing.T) {
+	router, _ := newRouter(t)
+	first, second := "/api/universe/"+itoa(create(t, router, blinker)), "/api/universe/"+itoa(create(t, router, blinker))
+	tests := []struct {
+		name   string
+		path   string
+		want   int
+		body   string
+		states map[string]string
+	}{
+		{"pause simulation", "/api/simulation/pause", http.StatusOK, `"paused":true`,
+			map[string]string{first: `"generation":0`, second: `"generation":0`}},
+		{"step simulation", "/api/simulation/step?n=3", http.StatusOK, `"paused":true`,
+			map[string]string{first: `"generation":3`, second: `"generation":3`}},
+		{"pause universe", first + "/pause", http.StatusOK, `"paused":true`, nil},
+		{"resume simulation", "/api/simulation/resume", http.StatusOK, `"paused":false`,
+			map[string]string{first: `"paused":true`, second: `"paused":false`}},
+		{"step universe", first + "/step", http.StatusOK, `"generation":4`,
+			map[string]string{first: `"paused":true`, second: `"generation":3`}},
+		{"resume universe", first + "/resume", http.StatusOK, `"paused":false`, nil},
+		{"step by 0", "/api/simulation/step?n=0", http.StatusBadRequest, "", nil},
+		{"step beyond the limit", first + "/step?n=1001", http.StatusBadRequest, "", nil},
+		{"step by a word", "/api/simulation/step?n=all", http.StatusBadRequest, "", nil},
+		{"step missing universe", "/api/universe/404/step?n=2", http.StatusNotFound, "", nil},
+		{"pause missing universe", "/api/universe/404/pause", http.StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		w := serve(router, http.MethodPost, tt.path, "")
+		if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: status %d, body %s, want %d with %s", tt.name, w.Code, w.Body, tt.want, tt.body)
+		}
+		for path, want := range tt.states {
+			if w = serve(router, http.MethodGet, path, ""); !strings.Contains(w.Body.String(), want) {
+				t.Errorf("%s: %s is %s, want %s", tt.name, path, w.Body, want)
+			}
+		}
+	}
+	// Rejected steps left universes alone.
+	if w := serve(router, http.MethodGet, first, ""); !strings.Contains(w.Body.String(), `"generation":4`) {
+		t.Errorf("universe stepped by rejected steps: %s", w.Body)
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	router, _ := newRouter(t)
+	id := "/api/universe/" + itoa(create(t, router, `{"cells": [[true]], "generations_per_tick": 4}`))
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+		result string
+	}{
+		{"fps", http.MethodPatch, "/api/simulation", `{"fps": 30}`, http.StatusOK, `"fps":30`},
+		{"fps 0", http.MethodPatch, "/api/simulation", `{"fps": 0}`, http.StatusBadRequest, ""},
+		{"fps beyond the limit", http.MethodPatch, "/api/simulation", `{"fps": 121}`, http.StatusBadRequest, ""},
+		{"unknown field", http.MethodPatch, "/api/simulation", `{"speed": 2}`, http.StatusBadRequest, ""},
+		{"kept fps", http.MethodGet, "/api/simulation", "", http.StatusOK, `"fps":30`},
+		{"created speed", http.MethodGet, id, "", http.StatusOK, `"generations_per_tick":4`},
+		{"speed", http.MethodPatch, id, `{"generations_per_tick": 100}`, http.StatusOK, `"generations_per_tick":100`},
+		{"speed 0", http.MethodPatch, id, `{"generations_per_tick": 0}`, http.StatusBadRequest, ""},
+		{"speed beyond the limit", http.MethodPatch, id, `{"generations_per_tick": 101}`, http.StatusBadRequest, ""},
+		{"created beyond the limit", http.MethodPost, "/api/universe", `{"cells": [[true]], "generations_per_tick": 101}`, http.StatusBadRequest, ""},
+		{"kept speed", http.MethodGet, id, "", http.StatusOK, `"generations_per_tick":100`},
+	}
+	for _, tt := range tests {
+		w := serve(router, tt.method, tt.path, tt.body)
+		if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.result) {
+			t.Errorf("%s: status %d, body %s, want %d with %s", tt.name, w.Code, w.Body, tt.want, tt.result)
+		}
+	}
+}
+
+func TestMergeUniverses(t *testing.T) {
+	router, _ := newRouter(t)
+	first := create(t, router, blinker)
+	highlife := create(t, router, `{"cells": [[true, true], [true, true]], "rule": "highlife"}`)
+	if w := serve(router, http.MethodPost, "/api/merge", ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("merge of different rules: status %d, want 422, body %s", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPost, "/api/merge", `{"gap": -1}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("merge with a negative gap: status %d, want 422, body %s", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodDelete, "/api/universe/"+itoa(highlife), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", w.Code)
+	}
+	create(t, router, `{"cells": [[true, true], [true, true]]}`)
+	w := serve(router, http.MethodPost, "/api/merge", `{"layout": "row", "gap": 2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("merge: status %d, body %s", w.Code, w.Body)
+	}
+	var merged struct {
+		ID uint64 `json:"id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&merged); err != nil || merged.ID <= first {
+		t.Errorf("merged into #%d, %v", merged.ID, err)
+	}
+	if w = serve(router, http.MethodGet, "/api/universe/"+itoa(first), ""); w.Code != http.StatusNotFound {
+		t.Errorf("merged universe #%d is still there", first)
+	}
+}
+
+func TestExportUniverse(t *testing.T) {
+	router, _ := newRouter(t)
+	id := create(t, router, blinker)
+	w := serve(router, http.MethodGet, "/api/universe/"+itoa(id)+"/export?format=cells", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status %d, body %s", w.Code, w.Body)
+	}
+	if w.Body.String() != "...\nOOO\n...\n" {
+		t.Errorf("exported %q", w.Body)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, "universe-"+itoa(id)+".cells") {
+		t.Errorf("Content-Disposition %q", got)
+	}
+}
+
+// itoa formats a universe ID
+func itoa(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
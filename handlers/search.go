@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ride90/game-of-life/internal/search"
+	"net/http"
+)
+
+// HandlerSearch handles requests for soup search results
+type HandlerSearch struct {
+	search *search.Search
+}
+
+// NewHandlerSearch creates a new instance of HandlerSearch
+func NewHandlerSearch(s *search.Search) HandlerSearch {
+	return HandlerSearch{search: s}
+}
+
+// Results handles the retrieval of tallies of objects & rare objects found so far
+func (h HandlerSearch) Results(w http.ResponseWriter, r *http.Request) {
+	err := json.NewEncoder(w).Encode(h.search.Results())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
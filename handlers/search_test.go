@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/ride90/game-of-life/internal/search"
+	"github.com/ride90/game-of-life/internal/universe"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchResults(t *testing.T) {
+	s, err := search.New(filepath.Join(t.TempDir(), "search.json"), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := universe.Seed(7)
+	s.Record(universe.Soup{Width: 8, Height: 8, Seed: &seed}, universe.ConwayRule, universe.Census{{Code: "xs4_33", Count: 3}, {Code: "xp2_7", Count: 1}})
+	router := mux.NewRouter()
+	router.HandleFunc("/api/search", NewHandlerSearch(s).Results).Methods(http.MethodGet)
+
+	w := serve(router, http.MethodGet, "/api/search", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var results search.Results
+	if err = json.NewDecoder(w.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if results.Soups != 1 || results.Objects != 4 || len(results.Tallies) != 2 {
+		t.Errorf("results %+v, want 1 soup of 4 objects of 2 kinds", results)
+	}
+	if len(results.Rare) != 1 || results.Rare[0].Code != "xp2_7" || *results.Rare[0].Soup.Seed != 7 {
+		t.Errorf("rare objects %+v, want the blinker from seed 7", results.Rare)
+	}
+}
@@ -0,0 +1,188 @@
+package multiverse
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Layout arranges universes of different sizes within a merged universe
+type Layout interface {
+	// Name returns the name the layout is registered under.
+	Name() string
+	// Arrange returns top left corners of rectangles of the given sizes, which
+	// don't overlap & are at least gap cells apart, and the bounding box size.
+	Arrange(sizes [][2]int, gap int) (positions [][2]int, width, height int)
+}
+
+// DefaultLayout is used when no layout is given.
+var DefaultLayout Layout = Grid{}
+
+// layouts holds all known layouts by name.
+var layouts = map[string]Layout{}
+
+func init() {
+	for _, l := range []Layout{Grid{}, Row{}, Shelf{}, Spiral{}} {
+		layouts[l.Name()] = l
+	}
+}
+
+// ParseLayout finds a layout by its name
+func ParseLayout(name string) (Layout, error) {
+	l, ok := layouts[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		names := make([]string, 0, len(layouts))
+		for n := range layouts {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown layout %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return l, nil
+}
+
+// Grid puts universes into rows of universesPerRow equal slots, the size of the largest universe
+type Grid struct{}
+
+// Name returns "grid"
+func (Grid) Name() string { return "grid" }
+
+// Arrange centres every universe in its slot
+func (Grid) Arrange(sizes [][2]int, gap int) ([][2]int, int, int) {
+	columns := universesPerRow
+	if len(sizes) < columns {
+		columns = len(sizes)
+	}
+	rows := (len(sizes) + columns - 1) / columns
+	slot := largest(sizes)
+	positions := make([][2]int, len(sizes))
+	for i, size := range sizes {
+		positions[i] = [2]int{
+			i%columns*(slot[0]+gap) + (slot[0]-size[0])/2,
+			i/columns*(slot[1]+gap) + (slot[1]-size[1])/2,
+		}
+	}
+	return positions, columns*(slot[0]+gap) - gap, rows*(slot[1]+gap) - gap
+}
+
+// Row puts universes next to each other, aligned to the top
+type Row struct{}
+
+// Name returns "row"
+func (Row) Name() string { return "row" }
+
+// Arrange places universes left to right
+func (Row) Arrange(sizes [][2]int, gap int) ([][2]int, int, int) {
+	positions := make([][2]int, len(sizes))
+	x, height := 0, 0
+	for i, size := range sizes {
+		positions[i] = [2]int{x, 0}
+		x += size[0] + gap
+		if size[1] > height {
+			height = size[1]
+		}
+	}
+	return positions, x - gap, height
+}
+
+// Shelf packs universes, tallest first, onto shelves of a roughly square bin
+type Shelf struct{}
+
+// Name returns "shelf"
+func (Shelf) Name() string { return "shelf" }
+
+// Arrange fills shelves left to right and starts a new one when a universe doesn't fit
+func (Shelf) Arrange(sizes [][2]int, gap int) ([][2]int, int, int) {
+	order := make([]int, len(sizes))
+	var area float64
+	for i, size := range sizes {
+		order[i] = i
+		area += float64((size[0] + gap) * (size[1] + gap))
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]][1] > sizes[order[j]][1]
+	})
+	binWidth := int(math.Ceil(math.Sqrt(area)))
+	if widest := largest(sizes)[0]; widest > binWidth {
+		binWidth = widest
+	}
+
+	positions := make([][2]int, len(sizes))
+	x, y, shelfHeight, width := 0, 0, 0, 0
+	for _, i := range order {
+		size := sizes[i]
+		if x > 0 && x+size[0] > binWidth {
+			x, y, shelfHeight = 0, y+shelfHeight+gap, 0
+		}
+		positions[i] = [2]int{x, y}
+		x += size[0] + gap
+		if x-gap > width {
+			width = x - gap
+		}
+		if size[1] > shelfHeight {
+			shelfHeight = size[1]
+		}
+	}
+	return positions, width, y + shelfHeight
+}
+
+// Spiral puts the first universe in the middle and the rest around it in a
+// square spiral of equal slots, the size of the largest universe
+type Spiral struct{}
+
+// Name returns "spiral"
+func (Spiral) Name() string { return "spiral" }
+
+// Arrange walks slots right, down, left & up with growing legs, centring every universe in its slot
+func (Spiral) Arrange(sizes [][2]int, gap int) ([][2]int, int, int) {
+	directions := [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	slots := make([][2]int, len(sizes))
+	var slot [2]int
+	minSlot, maxSlot := slot, slot
+	for i, leg, direction := 1, 1, 0; i < len(sizes); direction++ {
+		// Legs grow every second turn: 1, 1, 2, 2, 3, 3...
+		for step := 0; step < leg && i < len(sizes); step++ {
+			slot[0] += directions[direction%4][0]
+			slot[1] += directions[direction%4][1]
+			slots[i] = slot
+			i++
+			for axis := 0; axis < 2; axis++ {
+				if slot[axis] < minSlot[axis] {
+					minSlot[axis] = slot[axis]
+				}
+				if slot[axis] > maxSlot[axis] {
+					maxSlot[axis] = slot[axis]
+				}
+			}
+		}
+		if direction%2 == 1 {
+			leg++
+		}
+	}
+
+	size := largest(sizes)
+	positions := make([][2]int, len(sizes))
+	for i, s := range slots {
+		positions[i] = [2]int{
+			(s[0]-minSlot[0])*(size[0]+gap) + (size[0]-sizes[i][0])/2,
+			(s[1]-minSlot[1])*(size[1]+gap) + (size[1]-sizes[i][1])/2,
+		}
+	}
+	columns, rows := maxSlot[0]-minSlot[0]+1, maxSlot[1]-minSlot[1]+1
+	return positions, columns*(size[0]+gap) - gap, rows*(size[1]+gap) - gap
+}
+
+// largest returns the largest width & height among sizes
+func largest(sizes [][2]int) [2]int {
+	var size [2]int
+	for _, s := range sizes {
+		if s[0] > size[0] {
+			size[0] = s[0]
+		}
+		if s[1] > size[1] {
+			size[1] = s[1]
+		}
+	}
+	return size
+}
@@ -2,11 +2,12 @@ package multiverse
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ride90/game-of-life/configs"
+	"github.com/ride90/game-of-life/internal/pattern"
 	"github.com/ride90/game-of-life/internal/universe"
 	log "github.com/sirupsen/logrus"
-	"math"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +16,23 @@ import (
 const (
 	universesPerRow      = 4
 	mergedUniverseColour = "#F00"
+	// maxMergeGap limits the number of dead cells between merged universes.
+	maxMergeGap = 1024
+	// maxMergeColours is the size of the largest palette of a merged universe.
+	maxMergeColours = 256
+)
+
+var (
+	// ErrUniverseNotFound is returned when there's no universe with the given ID.
+	ErrUniverseNotFound = errors.New("universe not found")
+	// ErrMultiverseFull is returned when the maximum number of universes is reached.
+	ErrMultiverseFull = errors.New("multiverse is full")
+	// ErrCellBudgetExceeded is returned when universes would have more cells than allowed.
+	ErrCellBudgetExceeded = errors.New("cell budget exceeded")
+	// ErrUnsupportedKind is returned when an operation needs a Life universe.
+	ErrUnsupportedKind = errors.New("operation not supported by the kind of universe")
+	// ErrRuleMismatch is returned when universes of different rules or grids are merged.
+	ErrRuleMismatch = errors.New("universes follow different rules")
 )
 
 var mvCreateInstanceLock = &sync.Mutex{}
@@ -34,64 +52,181 @@ func GetInstance() *Multiverse {
 	return mvInstance
 }
 
-// Multiverse represents the collection of universes
+// Multiverse represents the collection of universes of all kinds
 type Multiverse struct {
-	universes [24]*universe.Universe
-	count     int
-	lock      sync.Mutex // Mutex for concurrent access control
+	universes []universe.Automaton
+	// maxUniverses limits the number of universes, 0 means no limit.
+	maxUniverses int
+	// maxTotalCells limits the number of cells of all universes together, 0 means no limit.
+	maxTotalCells int
+	// historyDepth is the number of previous generations Life universes keep.
+	historyDepth int
+	lastID       uint64
+	// paused freezes all universes, controls pause or speed up single ones by ID.
+	paused   bool
+	fps      int
+	controls map[uint64]*control
+	// fpsWatchers receive the tick rate whenever it changes.
+	fpsWatchers []chan int
+	lock        sync.Mutex // Mutex for concurrent access control
 }
 
 // newMultiverse creates a new instance of Multiverse
 func newMultiverse() *Multiverse {
-	mu := Multiverse{}
+	mu := Multiverse{fps: DefaultFps, controls: map[uint64]*control{}}
 	return &mu
 }
 
-// AppendUniverse adds a new universe to the end of the collection
-func (r *Multiverse) AppendUniverse(u *universe.Universe) {
+// SetLimits sets the maximum number of universes & cells of all universes together
+// 0 means no limit. Universes already there are kept even if they exceed new limits.
+func (r *Multiverse) SetLimits(maxUniverses, maxTotalCells int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.maxUniverses = maxUniverses
+	r.maxTotalCells = maxTotalCells
+}
+
+// SetHistoryDepth sets the number of previous generations Life universes keep to rewind to, 0 keeps none
+func (r *Multiverse) SetHistoryDepth(depth int) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	r.universes[r.count] = u
-	r.count++
+	r.historyDepth = depth
+	for _, u := range r.universes {
+		r.keepHistory(u)
+	}
+}
+
+// keepHistory makes a Life universe keep previous generations
+// Must be called with the lock held.
+func (r *Multiverse) keepHistory(u universe.Automaton) {
+	if life, ok := u.(*universe.Universe); ok {
+		life.SetHistoryDepth(r.historyDepth)
+	}
 }
 
-// PrependUniverse adds a new universe to the beginning of the collection
-func (r *Multiverse) PrependUniverse(u *universe.Universe) {
+// AppendUniverse adds a new universe to the end of the collection and returns its ID
+func (r *Multiverse) AppendUniverse(u universe.Automaton) (uint64, error) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
 	// Ensure we can fit a new universe.
-	if r.IsFull() {
-		return
+	if err := r.checkLimits(u); err != nil {
+		return 0, err
 	}
+	id := r.nextID()
+	u.SetID(id)
+	r.keepHistory(u)
+	r.universes = append(r.universes, u)
+	return id, nil
+}
 
-	// Move all universes to the right in an array (index++).
-	for i := len(r.universes) - 1; i >= 0; i-- {
-		if r.universes[i] == nil {
-			continue
+// PrependUniverse adds a new universe to the beginning of the collection and returns its ID
+func (r *Multiverse) PrependUniverse(u universe.Automaton) (uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Ensure we can fit a new universe.
+	if err := r.checkLimits(u); err != nil {
+		return 0, err
+	}
+	id := r.nextID()
+	u.SetID(id)
+	r.keepHistory(u)
+	r.universes = append([]universe.Automaton{u}, r.universes...)
+	return id, nil
+}
+
+// checkLimits ensures a new universe fits into the multiverse
+// Must be called with the lock held.
+func (r *Multiverse) checkLimits(u universe.Automaton) error {
+	if r.isFull() {
+		return fmt.Errorf("%w: %d universes at most", ErrMultiverseFull, r.maxUniverses)
+	}
+	return r.checkCells(r.totalCells() + u.Stats().Cells())
+}
+
+// checkCells ensures the multiverse can hold the given number of cells in total
+func (r *Multiverse) checkCells(cells int) error {
+	if r.maxTotalCells > 0 && cells > r.maxTotalCells {
+		return fmt.Errorf("%w: %d cells needed, %d cells at most", ErrCellBudgetExceeded, cells, r.maxTotalCells)
+	}
+	return nil
+}
+
+// totalCells returns the number of cells of all universes together
+// Must be called with the lock held.
+func (r *Multiverse) totalCells() int {
+	var cells int
+	for _, u := range r.universes {
+		cells += u.Stats().Cells()
+	}
+	return cells
+}
+
+// nextID returns a new universe ID, IDs are never reused
+func (r *Multiverse) nextID() uint64 {
+	r.lastID++
+	return r.lastID
+}
+
+// find returns the position of the universe with the given ID
+// Must be called with the lock held.
+func (r *Multiverse) find(id uint64) (int, error) {
+	for i, u := range r.universes {
+		if u.Stats().ID == id {
+			return i, nil
 		}
-		r.universes[i+1] = r.universes[i]
 	}
-	r.universes[0] = u
-	r.count++
+	return 0, fmt.Errorf("%w: #%d", ErrUniverseNotFound, id)
+}
+
+// findLife returns the Life universe with the given ID
+// Must be called with the lock held.
+func (r *Multiverse) findLife(id uint64) (*universe.Universe, error) {
+	i, err := r.find(id)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := r.universes[i].(*universe.Universe)
+	if !ok {
+		return nil, fmt.Errorf("%w: #%d is of kind %q", ErrUnsupportedKind, id, r.universes[i].Kind())
+	}
+	return u, nil
 }
 
 // IsFull checks if the Multiverse is full
 func (r *Multiverse) IsFull() bool {
-	return r.count >= len(r.universes)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.isFull()
+}
+
+// isFull checks if the Multiverse is full, must be called with the lock held
+func (r *Multiverse) isFull() bool {
+	return r.maxUniverses > 0 && len(r.universes) >= r.maxUniverses
 }
 
 // String returns a string representation of the Multiverse
 func (r *Multiverse) String() string {
-	return fmt.Sprintf("Multiverse with %d/%d universes", r.count, len(r.universes))
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.describe()
+}
+
+// describe returns a string representation of the Multiverse, must be called with the lock held
+func (r *Multiverse) describe() string {
+	return fmt.Sprintf("Multiverse with %d universes, %d cells", len(r.universes), r.totalCells())
 }
 
-// RenderMatrices returns a string containing rendered matrices of contained universes
+// RenderMatrices returns a string containing rendered matrices of contained Life universes
 // Used for stdout & debug purposes.
 func (r *Multiverse) RenderMatrices() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	var matricesStringBuilder strings.Builder
-	for i, u := range r.universes {
-		if u == nil {
+	for i, a := range r.universes {
+		u, ok := a.(*universe.Universe)
+		if !ok {
 			continue
 		}
 		matricesStringBuilder.WriteString(
@@ -104,133 +239,434 @@ func (r *Multiverse) RenderMatrices() string {
 	return matricesStringBuilder.String()
 }
 
-// Evolve evolves all universes in the Multiverse
+// Evolve evolves all running universes in the Multiverse
+// Nothing evolves nor gets removed while the simulation is paused, paused
+// universes are kept even if they're stale.
 func (r *Multiverse) Evolve(cfg *configs.Config) {
 	// Lock & Unlock.
 	r.lock.Lock()
 	defer func() {
 		r.lock.Unlock()
 	}()
+	if r.paused {
+		return
+	}
 
+	r.evolve(r.running(), 1, true)
+
+	// Remove stale static & periodic universes, keeping the order of the rest.
+	kept := r.universes[:0]
+	for _, u := range r.universes {
+		if stats := u.Stats(); stats.Settled && !r.control(stats.ID).paused {
+			duration := time.Now().UTC().Sub(stats.SettledFrom)
+			if cfg.Game.RemoveStaticUniverseAfter <= int(duration.Seconds()) {
+				if life, ok := u.(*universe.Universe); ok {
+					log.Infof("Removing stale settled %s Census: %s", life, life.Census())
+				} else {
+					log.Infof("Removing stale settled %s", u)
+				}
+				continue
+			}
+		}
+		kept = append(kept, u)
+	}
+	// Drop references to removed universes -> garbage collected.
+	for i := len(kept); i < len(r.universes); i++ {
+		r.universes[i] = nil
+	}
+	r.universes = kept
+	r.forgetControls()
+}
+
+// evolve evolves universes by the given number of generations, or ticks of
+// their own speed if perTick is set
+// Must be called with the lock held.
+func (r *Multiverse) evolve(universes []universe.Automaton, generations int, perTick bool) {
 	// Each universe evolves itself in a goroutine.
 	var wg sync.WaitGroup
-	for _, u := range r.universes {
-		if u == nil {
-			continue
+	for _, u := range universes {
+		n := generations
+		if perTick {
+			n *= r.control(u.Stats().ID).generationsPerTick
 		}
 		// TODO: Think/research if closure approach is better here:
 		//   https://go.dev/doc/faq#closures_and_goroutines
 		wg.Add(1)
-		go func(u *universe.Universe, wg *sync.WaitGroup) {
+		go func(u universe.Automaton, wg *sync.WaitGroup) {
 			defer wg.Done()
-			u.Evolve()
+			for i := 0; i < n; i++ {
+				u.Evolve()
+			}
 		}(u, &wg)
 	}
 	wg.Wait()
+}
 
-	// Remove stale static universes.
-	indicesToRemove := make([]int, 0, 8)
-	for i, u := range r.universes {
-		if u != nil && u.IsStatic {
-			duration := time.Now().UTC().Sub(u.StaticFrom)
-			if cfg.Game.RemoveStaticUniverseAfter <= int(duration.Seconds()) {
-				indicesToRemove = append(indicesToRemove, i)
-			}
-		}
+// UniverseJSON serializes the universe with the given ID to JSON format
+func (r *Multiverse) UniverseJSON(id uint64) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	i, err := r.find(id)
+	if err != nil {
+		return nil, err
 	}
-	if len(indicesToRemove) > 0 {
-		// Remove references to stale universes -> garbage collected.
-		for _, i := range indicesToRemove {
-			log.Info("Removing stale static", r.universes[i])
-			r.universes[i] = nil
-		}
-		// Squash left non-nil elements.
-		tmpArr := [24]*universe.Universe{}
-		tmpIndex := 0
-		for i := range r.universes {
-			if r.universes[i] == nil {
-				continue
-			}
-			tmpArr[tmpIndex] = r.universes[i]
-			tmpIndex++
-		}
-		r.universes = tmpArr
-		r.count = tmpIndex
+	return r.encode(r.universes[i])
+}
+
+// UpdateUniverse applies changes to the Life universe with the given ID
+func (r *Multiverse) UpdateUniverse(id uint64, patch universe.Patch) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	u, err := r.findLife(id)
+	if err != nil {
+		return err
+	}
+	return u.Apply(patch)
+}
+
+// RewindUniverse turns the Life universe with the given ID back by the given number of generations
+func (r *Multiverse) RewindUniverse(id uint64, generations int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	u, err := r.findLife(id)
+	if err != nil {
+		return err
 	}
+	return u.Rewind(generations)
+}
+
+// RemoveUniverse removes the universe with the given ID
+func (r *Multiverse) RemoveUniverse(id uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	i, err := r.find(id)
+	if err != nil {
+		return err
+	}
+	log.Infoln("Removing universe", r.universes[i])
+	r.remove(i)
+	return nil
+}
+
+// remove removes the i-th universe, keeping the order of the rest
+// Must be called with the lock held.
+func (r *Multiverse) remove(i int) {
+	last := len(r.universes) - 1
+	copy(r.universes[i:], r.universes[i+1:])
+	// Drop the reference left in the backing array -> garbage collected.
+	r.universes[last] = nil
+	r.universes = r.universes[:last]
+}
+
+// TakeUniverse removes & returns the universe with the given ID once the condition holds
+// for its stats, nil is returned while it doesn't.
+func (r *Multiverse) TakeUniverse(id uint64, condition func(universe.Stats) bool) (universe.Automaton, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	i, err := r.find(id)
+	if err != nil {
+		return nil, err
+	}
+	u := r.universes[i]
+	if !condition(u.Stats()) {
+		return nil, nil
+	}
+	r.remove(i)
+	return u, nil
+}
+
+// Census returns a census of objects of the Life universe with the given ID
+// Objects are identified after the lock is released, universes keep evolving meanwhile.
+func (r *Multiverse) Census(id uint64) (universe.Census, error) {
+	r.lock.Lock()
+	u, err := r.findLife(id)
+	if err != nil {
+		r.lock.Unlock()
+		return nil, err
+	}
+	census := u.PrepareCensus()
+	r.lock.Unlock()
+	return census(), nil
+}
+
+// Pattern returns cells & rule of the Life universe with the given ID for export
+func (r *Multiverse) Pattern(id uint64) (*pattern.Pattern, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	u, err := r.findLife(id)
+	if err != nil {
+		return nil, err
+	}
+	return u.Pattern()
 }
 
 // Reset clears the Multiverse
 func (r *Multiverse) Reset() {
-	log.Infoln("Reset multiverse", r)
-	r.universes = [24]*universe.Universe{}
-	r.count = 0
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reset()
+}
+
+// reset clears the Multiverse, must be called with the lock held
+func (r *Multiverse) reset() {
+	log.Infoln("Reset multiverse", r.describe())
+	r.universes = nil
+}
+
+// Split cuts the Life universe with the given ID into new universes and returns their IDs
+// The source universe is removed, pieces are added at the beginning or the end.
+func (r *Multiverse) Split(id uint64, opts universe.SplitOptions, prepend bool) ([]uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	source, err := r.findLife(id)
+	if err != nil {
+		return nil, err
+	}
+	i, _ := r.find(id)
+	pieces, err := source.Split(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The source universe makes room for its pieces.
+	count, cells := len(r.universes)-1+len(pieces), r.totalCells()-source.Width()*source.Height()
+	if r.maxUniverses > 0 && count > r.maxUniverses {
+		return nil, fmt.Errorf("%w: %d universes at most, %d needed", ErrMultiverseFull, r.maxUniverses, count)
+	}
+	for _, piece := range pieces {
+		cells += piece.Width() * piece.Height()
+	}
+	if err = r.checkCells(cells); err != nil {
+		return nil, err
+	}
+
+	log.Infoln("Splitting universe", source, "into", len(pieces), "universes")
+	rest := append(r.universes[:i:i], r.universes[i+1:]...)
+	ids := make([]uint64, len(pieces))
+	added := make([]universe.Automaton, len(pieces))
+	for j, piece := range pieces {
+		piece.ID = r.nextID()
+		r.keepHistory(piece)
+		ids[j] = piece.ID
+		added[j] = piece
+	}
+	if prepend {
+		r.universes = append(added, rest...)
+	} else {
+		r.universes = append(rest, added...)
+	}
+	return ids, nil
+}
+
+// MergeOptions holds settings of a merge
+type MergeOptions struct {
+	// IDs lists universes to merge, all of them if empty.
+	IDs []uint64 `json:"ids"`
+	// Layout is the name of a layout, DefaultLayout if empty.
+	Layout string `json:"layout"`
+	// Gap is the number of dead cells between universes, also across wrapped edges.
+	Gap int `json:"gap"`
 }
 
-// Merge merges all universes together into one big madness
-func (r *Multiverse) Merge() {
+// Merge merges Life universes together into one big madness and returns its ID
+// Every cell keeps the colour of the universe it comes from. The merged
+// universe takes the place of the first source, other universes stay.
+// Universes of other kinds are left out of a merge of all universes, sources
+// must share the rule & the grid, which the merged universe follows too.
+func (r *Multiverse) Merge(opts MergeOptions) (uint64, error) {
+	layout := DefaultLayout
+	if opts.Layout != "" {
+		var err error
+		if layout, err = ParseLayout(opts.Layout); err != nil {
+			return 0, err
+		}
+	}
+	if opts.Gap < 0 || opts.Gap > maxMergeGap {
+		return 0, fmt.Errorf("gap must be between 0 and %d, got %d", maxMergeGap, opts.Gap)
+	}
+	if len(opts.IDs) == 1 {
+		return 0, fmt.Errorf("at least 2 universes are needed for a merge, got 1")
+	}
+
 	// Lock & Unlock.
 	r.lock.Lock()
 	defer func() {
 		r.lock.Unlock()
 	}()
 
+	// Find universes to merge, in the order they're listed.
+	var sources []*universe.Universe
+	isSource := map[uint64]bool{}
+	if len(opts.IDs) == 0 {
+		for _, a := range r.universes {
+			if u, ok := a.(*universe.Universe); ok {
+				isSource[u.ID] = true
+				sources = append(sources, u)
+			}
+		}
+	}
+	for _, id := range opts.IDs {
+		if isSource[id] {
+			return 0, fmt.Errorf("universe #%d is listed more than once", id)
+		}
+		u, err := r.findLife(id)
+		if err != nil {
+			return 0, err
+		}
+		isSource[id] = true
+		sources = append(sources, u)
+	}
+
 	// Check if it makes sense to perform merge
-	if r.count <= 1 {
-		log.Warn("Merge doesn't make sense", r)
-		return
+	if len(sources) <= 1 {
+		log.Warn("Merge doesn't make sense ", r.describe())
+		return 0, nil
+	}
+
+	// The merged universe follows the rule of its sources, cells of other rules would evolve differently.
+	rule, grid := sources[0].Rule, sources[0].Grid
+	for _, u := range sources[1:] {
+		if u.Rule.String() != rule.String() || u.Grid.Name() != grid.Name() {
+			return 0, fmt.Errorf(
+				"%w: #%d is %s on the %s grid, #%d is %s on the %s grid",
+				ErrRuleMismatch, sources[0].ID, rule, grid.Name(), u.ID, u.Rule, u.Grid.Name(),
+			)
+		}
 	}
 
-	log.Infoln("Performing universes merge", r)
+	log.Infoln("Performing universes merge", r.describe(), "Universes:", len(sources), "Layout:", layout.Name(), "Gap:", opts.Gap)
 
-	// Create matrix to fit all universes.
-	normalMatrixSize := len(r.universes[0].Matrix[0])
-	var finalMatrix [][]bool
-	finalMatrixHeight := int(math.Ceil(float64(r.count)/universesPerRow)) * normalMatrixSize
-	finalMatrixWidth := normalMatrixSize * universesPerRow
-	finalMatrix = make([][]bool, finalMatrixHeight)
+	// Arrange universes & create matrices to fit all of them, the trailing
+	// gap separates universes across wrapped edges.
+	sizes := make([][2]int, len(sources))
+	sourceCells := 0
+	for i, u := range sources {
+		sizes[i] = [2]int{u.Width(), u.Height()}
+		sourceCells += u.Width() * u.Height()
+	}
+	positions, finalMatrixWidth, finalMatrixHeight := layout.Arrange(sizes, opts.Gap)
+	finalMatrixWidth += opts.Gap
+	finalMatrixHeight += opts.Gap
+	if err := r.checkCells(r.totalCells() - sourceCells + finalMatrixWidth*finalMatrixHeight); err != nil {
+		return 0, err
+	}
+	finalMatrix := make([][]bool, finalMatrixHeight)
+	finalColours := make([][]uint8, finalMatrixHeight)
+	var finalStates [][]uint8
+	if rule.IsGenerations() {
+		finalStates = make([][]uint8, finalMatrixHeight)
+	}
 	for i := range finalMatrix {
 		finalMatrix[i] = make([]bool, finalMatrixWidth)
+		finalColours[i] = make([]uint8, finalMatrixWidth)
+		if finalStates != nil {
+			finalStates[i] = make([]uint8, finalMatrixWidth)
+		}
 	}
 
-	// Fit "normal" matrices into a "big" final one.
-	// Having 3 nested loops is fine here since we are merging array of
-	// into one matrix. It's happening only once when merge is triggered.
-	var finalX, finalY = 0, 0
-	for indexUniverse, universe := range r.universes {
-		if universe == nil {
-			break
+	// Fit matrices into the final one, mapping colours of universes into a shared palette.
+	// Colours of multi-colour rules are species, they keep the palette of the first universe.
+	var palette []string
+	if rule.Species > 0 {
+		palette = append([]string(nil), sources[0].Palette()...)
+	}
+	paletteIndices := map[string]uint8{}
+	colourIndex := func(colour string) (uint8, error) {
+		if index, ok := paletteIndices[colour]; ok {
+			return index, nil
 		}
-		if indexUniverse%universesPerRow == 0 {
-			finalX = 0
-		} else {
-			finalY = indexUniverse / universesPerRow * normalMatrixSize
+		if len(palette) == maxMergeColours {
+			return 0, fmt.Errorf("merged universes have more than %d colours", maxMergeColours)
+		}
+		palette = append(palette, colour)
+		paletteIndices[colour] = uint8(len(palette) - 1)
+		return paletteIndices[colour], nil
+	}
+	var maxPeriod int
+	for i, u := range sources {
+		if u.MaxPeriod > maxPeriod {
+			maxPeriod = u.MaxPeriod
+		}
+		// Multi-colour universes keep their colours, others have a single one.
+		sourcePalette := u.Palette()
+		if sourcePalette == nil {
+			sourcePalette = []string{u.Colour}
+		}
+		indices := make([]uint8, len(sourcePalette))
+		for j, colour := range sourcePalette {
+			if rule.Species > 0 {
+				indices[j] = uint8(j)
+				continue
+			}
+			index, err := colourIndex(colour)
+			if err != nil {
+				return 0, err
+			}
+			indices[j] = index
 		}
 
-		for y := range universe.Matrix {
-			for x := range universe.Matrix[y] {
-				finalMatrix[finalY][finalX] = universe.Matrix[y][x]
-				finalX++
+		finalX, finalY := positions[i][0], positions[i][1]
+		matrix := u.Cells()
+		for y := range matrix {
+			for x := range matrix[y] {
+				if matrix[y][x] {
+					finalMatrix[finalY+y][finalX+x] = true
+					finalColours[finalY+y][finalX+x] = indices[u.CellColour(x, y)]
+				}
 			}
-			finalY++
-			finalX = (indexUniverse % universesPerRow) * 50
+		}
+		for y, row := range u.States() {
+			copy(finalStates[finalY+y][finalX:], row)
 		}
 	}
 
-	// Create a universe which will keep all universes inside
-	finalUniverse := universe.Universe{
-		Colour: mergedUniverseColour,
-		Matrix: finalMatrix,
+	// Create a universe which will keep all universes inside, colours of
+	// cells need the square grid.
+	finalOptions := universe.Options{
+		Colour:    mergedUniverseColour,
+		Rule:      rule,
+		Grid:      grid,
+		MaxPeriod: maxPeriod,
+		States:    finalStates,
+	}
+	if _, ok := grid.(universe.Square); ok {
+		finalOptions.Palette, finalOptions.Colours = palette, finalColours
+	}
+	finalUniverse, err := universe.New(finalMatrix, finalOptions)
+	if err != nil {
+		log.Error("Merge failed: ", err)
+		return 0, err
 	}
 
-	// Reset multiverse -> remove all universes & reset count
-	r.Reset()
-
-	// Add final universe
-	r.universes[r.count] = &finalUniverse
-	r.count++
+	// Replace the first source with the final universe & remove the rest of them.
+	finalUniverse.ID = r.nextID()
+	r.keepHistory(finalUniverse)
+	merged := make([]universe.Automaton, 0, len(r.universes)-len(sources)+1)
+	for _, u := range r.universes {
+		switch {
+		case u == universe.Automaton(sources[0]):
+			merged = append(merged, finalUniverse)
+		case isSource[u.Stats().ID]:
+			continue
+		default:
+			merged = append(merged, u)
+		}
+	}
+	r.universes = merged
+	return finalUniverse.ID, nil
 }
 
 // ToJSON serializes the Multiverse to JSON format
 func (r *Multiverse) ToJSON() ([]byte, error) {
-	return json.Marshal(r.universes[:r.count])
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	universes := make([]json.RawMessage, len(r.universes))
+	for i, u := range r.universes {
+		data, err := r.encode(u)
+		if err != nil {
+			return nil, err
+		}
+		universes[i] = data
+	}
+	return json.Marshal(universes)
 }
@@ -0,0 +1,561 @@
+package multiverse
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ride90/game-of-life/configs"
+	"github.com/ride90/game-of-life/internal/universe"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newLife creates a Life universe from rows of cells, "o" is alive
+func newLife(t *testing.T, opts universe.Options, rows ...string) *universe.Universe {
+	t.Helper()
+	cells := make([][]bool, len(rows))
+	for y, row := range rows {
+		cells[y] = make([]bool, len(row))
+		for x, c := range row {
+			cells[y][x] = c == 'o'
+		}
+	}
+	if opts.Rule == (universe.Rule{}) {
+		opts.Rule = universe.ConwayRule
+	}
+	u, err := universe.New(cells, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// appendLife appends Life universes to the multiverse & returns their IDs
+func appendLife(t *testing.T, mv *Multiverse, universes ...*universe.Universe) []uint64 {
+	t.Helper()
+	ids := make([]uint64, len(universes))
+	for i, u := range universes {
+		var err error
+		if ids[i], err = mv.AppendUniverse(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func TestRemoveUniverse(t *testing.T) {
+	mv := newMultiverse()
+	ids := appendLife(t, mv,
+		newLife(t, universe.Options{}, "o"),
+		newLife(t, universe.Options{}, "oo"),
+		newLife(t, universe.Options{}, "ooo"),
+	)
+	if err := mv.RemoveUniverse(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := mv.RemoveUniverse(ids[0]); !errors.Is(err, ErrUniverseNotFound) {
+		t.Errorf("removing a removed universe: %v, want ErrUniverseNotFound", err)
+	}
+	if len(mv.universes) != 2 || mv.universes[0].Stats().ID != ids[1] || mv.universes[1].Stats().ID != ids[2] {
+		t.Errorf("universes %v left, want #%d & #%d in order", mv.universes, ids[1], ids[2])
+	}
+	// The slot left behind doesn't keep the last universe alive.
+	if backing := mv.universes[:3]; backing[2] != nil {
+		t.Errorf("backing array still references %s", backing[2])
+	}
+
+	taken, err := mv.TakeUniverse(ids[2], func(universe.Stats) bool { return true })
+	if err != nil || taken == nil || taken.Stats().ID != ids[2] {
+		t.Fatalf("took %v, %v", taken, err)
+	}
+	if backing := mv.universes[:2]; len(mv.universes) != 1 || backing[1] != nil {
+		t.Errorf("backing array still references %s", backing[1])
+	}
+}
+
+func TestLimits(t *testing.T) {
+	mv := newMultiverse()
+	mv.SetLimits(2, 10)
+	appendLife(t, mv, newLife(t, universe.Options{}, "ooo", "..."))
+	if _, err := mv.AppendUniverse(newLife(t, universe.Options{}, "ooo", "...", "...")); !errors.Is(err, ErrCellBudgetExceeded) {
+		t.Errorf("appending 9 cells to 6: %v, want ErrCellBudgetExceeded", err)
+	}
+	if _, err := mv.PrependUniverse(newLife(t, universe.Options{}, "oooo")); err != nil {
+		t.Errorf("prepending 4 cells to 6: %v", err)
+	}
+	if !mv.IsFull() {
+		t.Errorf("multiverse of 2 universes isn't full")
+	}
+	if _, err := mv.AppendUniverse(newLife(t, universe.Options{}, "o")); !errors.Is(err, ErrMultiverseFull) {
+		t.Errorf("appending a 3rd universe: %v, want ErrMultiverseFull", err)
+	}
+
+	// Universes already there are kept under lower limits, new ones don't fit.
+	mv.SetLimits(1, 0)
+	if len(mv.universes) != 2 {
+		t.Errorf("%d universes kept, want 2", len(mv.universes))
+	}
+	if _, err := mv.AppendUniverse(newLife(t, universe.Options{}, "o")); !errors.Is(err, ErrMultiverseFull) {
+		t.Errorf("appending beyond a lowered limit: %v, want ErrMultiverseFull", err)
+	}
+	mv.SetLimits(0, 0)
+	if _, err := mv.AppendUniverse(newLife(t, universe.Options{}, "o")); err != nil {
+		t.Errorf("appending without limits: %v", err)
+	}
+}
+
+func TestStringWhileChanging(t *testing.T) {
+	mv := newMultiverse()
+	block := newLife(t, universe.Options{}, "oo", "oo")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			id, err := mv.AppendUniverse(block)
+			if err == nil {
+				err = mv.RemoveUniverse(id)
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = mv.String()
+	}
+	<-done
+	if got := mv.String(); got != "Multiverse with 0 universes, 0 cells" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+// life returns the Life universe at the i-th position of the multiverse
+func life(t *testing.T, mv *Multiverse, i int) *universe.Universe {
+	t.Helper()
+	u, ok := mv.universes[i].(*universe.Universe)
+	if !ok {
+		t.Fatalf("universe at %d is a %s one", i, mv.universes[i].Kind())
+	}
+	return u
+}
+
+// rows renders cells as rows, "o" is alive
+func rows(cells [][]bool) []string {
+	rendered := make([]string, len(cells))
+	for y, row := range cells {
+		b := make([]byte, len(row))
+		for x, alive := range row {
+			b[x] = '.'
+			if alive {
+				b[x] = 'o'
+			}
+		}
+		rendered[y] = string(b)
+	}
+	return rendered
+}
+
+func TestMerge(t *testing.T) {
+	mv := newMultiverse()
+	ids := appendLife(t, mv,
+		newLife(t, universe.Options{Colour: "#111"}, "...", "ooo", "..."),
+		newLife(t, universe.Options{Colour: "#222"}, "oo", "oo"),
+	)
+	id, err := mv.Merge(MergeOptions{Layout: "row", Gap: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= ids[1] || len(mv.universes) != 1 || mv.universes[0].Stats().ID != id {
+		t.Fatalf("merged #%d into %v", id, mv.universes)
+	}
+	merged := life(t, mv, 0)
+	want := []string{"....oo.", "ooo.oo.", ".......", "......."}
+	if got := rows(merged.Cells()); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("merged cells %v, want %v", got, want)
+	}
+	if palette := merged.Palette(); strings.Join(palette, ",") != "#111,#222" {
+		t.Errorf("palette %v", palette)
+	}
+	if merged.CellColour(0, 1) != 0 || merged.CellColour(4, 0) != 1 || merged.CellColour(5, 1) != 1 {
+		t.Errorf("cells lost colours of their universes")
+	}
+	if merged.Rule != universe.ConwayRule {
+		t.Errorf("merged rule %s", merged.Rule)
+	}
+}
+
+func TestMergeKeepsRule(t *testing.T) {
+	generations := universe.MustParseRule("B2/S/3")
+	tests := []struct {
+		name string
+		opts universe.Options
+	}{
+		{"highlife", universe.Options{Rule: universe.MustParseRule("B36/S23")}},
+		{"generations", universe.Options{Rule: generations}},
+		{"hex", universe.Options{Rule: universe.MustParseRule("B2/S34"), Grid: universe.Hex{}}},
+		{"immigration", universe.Options{Rule: universe.MustParseRule("immigration")}},
+	}
+	for _, tt := range tests {
+		mv := newMultiverse()
+		opts := tt.opts
+		if tt.opts.Rule.Species > 0 {
+			opts.Colours = [][]uint8{{1, 0}, {0, 0}}
+		}
+		first := newLife(t, opts, "oo", "..")
+		if tt.opts.Rule.IsGenerations() {
+			first.Evolve()
+		}
+		appendLife(t, mv, first, newLife(t, tt.opts, "o.", ".o"))
+		if _, err := mv.Merge(MergeOptions{Layout: "row"}); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		merged := life(t, mv, 0)
+		if merged.Rule.String() != tt.opts.Rule.String() || merged.Grid.Name() != first.Grid.Name() {
+			t.Errorf("%s: merged universe is %s on the %s grid", tt.name, merged.Rule, merged.Grid.Name())
+		}
+		if states := first.States(); states != nil {
+			merged := merged.States()
+			for y, row := range states {
+				for x, state := range row {
+					if merged[y][x] != state {
+						t.Errorf("%s: state of (%d, %d) is %d, want %d", tt.name, x, y, merged[y][x], state)
+					}
+				}
+			}
+		}
+		if tt.opts.Rule.Species > 0 {
+			// Species are kept, not mapped into a palette of distinct colours.
+			if len(merged.Palette()) != tt.opts.Rule.Species || merged.CellColour(0, 0) != 1 || merged.CellColour(2, 0) != 0 {
+				t.Errorf("%s: palette %v, species %d & %d", tt.name, merged.Palette(), merged.CellColour(0, 0), merged.CellColour(2, 0))
+			}
+		}
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     MergeOptions
+		other    universe.Options
+		wantErr  error
+		wantKept int
+	}{
+		{"different rules", MergeOptions{}, universe.Options{Rule: universe.MustParseRule("B36/S23")}, ErrRuleMismatch, 2},
+		{"different grids", MergeOptions{}, universe.Options{Rule: universe.MustParseRule("B3/S23"), Grid: universe.Hex{}}, ErrRuleMismatch, 2},
+		{"missing universe", MergeOptions{IDs: []uint64{1, 404}}, universe.Options{}, ErrUniverseNotFound, 2},
+		{"cell budget", MergeOptions{Gap: 10}, universe.Options{}, ErrCellBudgetExceeded, 2},
+	}
+	for _, tt := range tests {
+		mv := newMultiverse()
+		mv.SetLimits(0, 100)
+		appendLife(t, mv, newLife(t, universe.Options{}, "oo", "oo"), newLife(t, tt.other, "oo", "oo"))
+		if _, err := mv.Merge(tt.opts); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if len(mv.universes) != tt.wantKept {
+			t.Errorf("%s: %d universes left, want %d", tt.name, len(mv.universes), tt.wantKept)
+		}
+	}
+
+	mv := newMultiverse()
+	ids := appendLife(t, mv, newLife(t, universe.Options{}, "o"), newLife(t, universe.Options{}, "o"))
+	for _, opts := range []MergeOptions{
+		{Gap: -1},
+		{Gap: maxMergeGap + 1},
+		{Layout: "circle"},
+		{IDs: ids[:1]},
+		{IDs: []uint64{ids[0], ids[0]}},
+	} {
+		if _, err := mv.Merge(opts); err == nil {
+			t.Errorf("merge %+v succeeded", opts)
+		}
+	}
+}
+
+func TestLayouts(t *testing.T) {
+	sizes := [][2]int{{3, 3}, {10, 2}, {1, 7}, {4, 4}, {2, 2}, {6, 1}}
+	const gap = 2
+	for name, layout := range layouts {
+		positions, width, height := layout.Arrange(sizes, gap)
+		for i, p := range positions {
+			if p[0] < 0 || p[1] < 0 || p[0]+sizes[i][0] > width || p[1]+sizes[i][1] > height {
+				t.Errorf("%s: universe %d at %v is outside %dx%d", name, i, p, width, height)
+			}
+			for j := 0; j < i; j++ {
+				q := positions[j]
+				apart := p[0] >= q[0]+sizes[j][0]+gap || q[0] >= p[0]+sizes[i][0]+gap ||
+					p[1] >= q[1]+sizes[j][1]+gap || q[1] >= p[1]+sizes[i][1]+gap
+				if !apart {
+					t.Errorf("%s: universes %d at %v & %d at %v are closer than %d cells", name, i, p, j, q, gap)
+				}
+			}
+		}
+	}
+}
+
+func TestMergeSubset(t *testing.T) {
+	mv := newMultiverse()
+	ids := appendLife(t, mv,
+		newLife(t, universe.Options{Colour: "#111"}, "o"),
+		newLife(t, universe.Options{Colour: "#222"}, "oo"),
+		newLife(t, universe.Options{Colour: "#333"}, "ooo"),
+	)
+	wireworld, err := universe.NewWireworld([][]uint8{{1, 2, 3, 3}}, "#444", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = mv.AppendUniverse(wireworld); err != nil {
+		t.Fatal(err)
+	}
+
+	// Listed universes are arranged in order & the merged one takes the place of the first.
+	id, err := mv.Merge(MergeOptions{IDs: []uint64{ids[2], ids[0]}, Layout: "row"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []uint64
+	for _, u := range mv.universes {
+		order = append(order, u.Stats().ID)
+	}
+	if len(order) != 3 || order[0] != ids[1] || order[1] != id || order[2] != wireworld.Stats().ID {
+		t.Fatalf("universes %v after merge, want #%d, #%d & the Wireworld one", order, ids[1], id)
+	}
+	if got := rows(life(t, mv, 1).Cells()); strings.Join(got, "|") != "oooo" {
+		t.Errorf("merged cells %v, want [oooo]", got)
+	}
+	if palette := life(t, mv, 1).Palette(); strings.Join(palette, ",") != "#333,#111" {
+		t.Errorf("palette %v, want colours in the listed order", palette)
+	}
+
+	// Other kinds can't be listed & are left out of a merge of all universes.
+	if _, err = mv.Merge(MergeOptions{IDs: []uint64{ids[1], wireworld.Stats().ID}}); !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("merging a Wireworld universe: %v, want ErrUnsupportedKind", err)
+	}
+	if _, err = mv.Merge(MergeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mv.universes) != 2 || mv.universes[1] != universe.Automaton(wireworld) {
+		t.Errorf("universes %v after merging all", mv.universes)
+	}
+
+	// A single Life universe left makes no merge.
+	if id, err = mv.Merge(MergeOptions{}); id != 0 || err != nil {
+		t.Errorf("merge of a single universe gave #%d, %v", id, err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	mv := newMultiverse()
+	ids := appendLife(t, mv,
+		newLife(t, universe.Options{}, "o"),
+		newLife(t, universe.Options{}, "o..o", "....", "o..o"),
+	)
+	pieces, err := mv.Split(ids[1], universe.SplitOptions{Mode: "components"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []uint64
+	for _, u := range mv.universes {
+		order = append(order, u.Stats().ID)
+	}
+	// On a torus all four corners are a single block.
+	if len(pieces) != 1 || len(order) != 2 || order[0] != pieces[0] || order[1] != ids[0] {
+		t.Errorf("split into %v, universes %v", pieces, order)
+	}
+	if got := rows(life(t, mv, 0).Cells()); strings.Join(got, "|") != "oo|oo" {
+		t.Errorf("component %v, want a block", got)
+	}
+
+	tests := []struct {
+		name          string
+		maxUniverses  int
+		maxTotalCells int
+		opts          universe.SplitOptions
+		wantErr       error
+	}{
+		// 4 tiles take the place of 1 universe.
+		{"too many universes", 3, 0, universe.SplitOptions{Columns: 2, Rows: 2}, ErrMultiverseFull},
+		// A single cell with a margin of 2 takes 25 cells.
+		{"too many cells", 0, 20, universe.SplitOptions{Mode: "components", Margin: 2}, ErrCellBudgetExceeded},
+		{"invalid grid", 0, 0, universe.SplitOptions{Columns: 5, Rows: 1}, nil},
+	}
+	for _, tt := range tests {
+		mv := newMultiverse()
+		ids := appendLife(t, mv, newLife(t, universe.Options{}, "o"), newLife(t, universe.Options{}, "o...", "....", "...."))
+		mv.SetLimits(tt.maxUniverses, tt.maxTotalCells)
+		_, err := mv.Split(ids[1], tt.opts, false)
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if len(mv.universes) != 2 {
+			t.Errorf("%s: %d universes left, want 2", tt.name, len(mv.universes))
+		}
+	}
+	if _, err := mv.Split(404, universe.SplitOptions{Columns: 2, Rows: 1}, false); !errors.Is(err, ErrUniverseNotFound) {
+		t.Errorf("splitting a missing universe: %v, want ErrUniverseNotFound", err)
+	}
+}
+
+// streamedUniverse holds the simulation state of a streamed universe
+type streamedUniverse struct {
+	ID                 uint64 `json:"id"`
+	Paused             bool   `json:"paused"`
+	GenerationsPerTick int    `json:"generations_per_tick"`
+}
+
+// streamed decodes universes of the stream
+func streamed(t *testing.T, mv *Multiverse) []streamedUniverse {
+	t.Helper()
+	data, err := mv.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var universes []streamedUniverse
+	if err = json.Unmarshal(data, &universes); err != nil {
+		t.Fatal(err)
+	}
+	return universes
+}
+
+// generations returns generation numbers of universes of the multiverse
+func generations(mv *Multiverse) []int {
+	var numbers []int
+	for _, u := range mv.universes {
+		numbers = append(numbers, u.Stats().Generation)
+	}
+	return numbers
+}
+
+func TestPause(t *testing.T) {
+	cfg := &configs.Config{}
+	cfg.Game.RemoveStaticUniverseAfter = 3600
+	mv := newMultiverse()
+	ids := appendLife(t, mv,
+		newLife(t, universe.Options{}, "...", "ooo", "..."),
+		newLife(t, universe.Options{}, "...", "ooo", "..."),
+	)
+	tests := []struct {
+		name   string
+		action func() error
+		want   []int
+		paused []bool
+	}{
+		{"paused", func() error { mv.Pause(); mv.Evolve(cfg); return nil }, []int{0, 0}, []bool{true, true}},
+		{"stepped", func() error { return mv.Step(3) }, []int{3, 3}, []bool{true, true}},
+		// A universe paused on its own stays paused when the simulation resumes.
+		{"resumed", func() error {
+			if err := mv.PauseUniverse(ids[0]); err != nil {
+				return err
+			}
+			mv.Resume()
+			mv.Evolve(cfg)
+			return nil
+		}, []int{3, 4}, []bool{true, false}},
+		{"stepped running", func() error { return mv.Step(2) }, []int{3, 6}, []bool{true, false}},
+		{"stepped one", func() error { return mv.StepUniverse(ids[0], MaxSteps) }, []int{3 + MaxSteps, 6}, []bool{true, false}},
+		{"resumed one", func() error { mv.ResumeUniverse(ids[0]); mv.Evolve(cfg); return nil }, []int{4 + MaxSteps, 7}, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		if err := tt.action(); err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if got := generations(mv); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: generations %v, want %v", tt.name, got, tt.want)
+		}
+		for i, u := range streamed(t, mv) {
+			if u.ID != ids[i] || u.Paused != tt.paused[i] {
+				t.Errorf("%s: universe #%d streamed paused %t, want %t", tt.name, u.ID, u.Paused, tt.paused[i])
+			}
+		}
+	}
+
+	for _, n := range []int{0, -1, MaxSteps + 1} {
+		if err := mv.Step(n); err == nil {
+			t.Errorf("step by %d succeeded", n)
+		}
+		if err := mv.StepUniverse(ids[0], n); err == nil {
+			t.Errorf("step of a universe by %d succeeded", n)
+		}
+	}
+	if got := generations(mv); !reflect.DeepEqual(got, []int{4 + MaxSteps, 7}) {
+		t.Errorf("rejected steps evolved universes to %v", got)
+	}
+	if err := mv.PauseUniverse(404); !errors.Is(err, ErrUniverseNotFound) {
+		t.Errorf("pausing a missing universe: %v, want ErrUniverseNotFound", err)
+	}
+}
+
+func TestUpdateSimulation(t *testing.T) {
+	mv := newMultiverse()
+	fpsChanges := mv.WatchFps()
+	for _, fps := range []int{0, -1, MaxFps + 1} {
+		if err := mv.UpdateSimulation(SimulationPatch{Fps: &fps}); err == nil {
+			t.Errorf("fps %d accepted", fps)
+		}
+	}
+	if got := mv.Simulation().Fps; got != DefaultFps {
+		t.Errorf("rejected fps changed the rate to %d", got)
+	}
+
+	// Watchers get the latest rate only, a rate not received yet is replaced.
+	for _, fps := range []int{1, MaxFps} {
+		if err := mv.UpdateSimulation(SimulationPatch{Fps: &fps}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case fps := <-fpsChanges:
+		if fps != MaxFps {
+			t.Errorf("watcher got fps %d, want %d", fps, MaxFps)
+		}
+	default:
+		t.Fatalf("watcher got no fps change")
+	}
+	select {
+	case fps := <-fpsChanges:
+		t.Errorf("watcher got a stale fps %d", fps)
+	default:
+	}
+	if got := mv.Simulation(); got.Fps != MaxFps || got.Paused {
+		t.Errorf("simulation %+v, want %d fps", got, MaxFps)
+	}
+}
+
+func TestGenerationsPerTick(t *testing.T) {
+	cfg := &configs.Config{}
+	cfg.Game.RemoveStaticUniverseAfter = 3600
+	mv := newMultiverse()
+	ids := appendLife(t, mv,
+		newLife(t, universe.Options{}, "...", "ooo", "..."),
+		newLife(t, universe.Options{}, "...", "ooo", "..."),
+	)
+	for _, n := range []int{0, -1, MaxGenerationsPerTick + 1} {
+		if err := mv.SetGenerationsPerTick(ids[0], n); err == nil {
+			t.Errorf("%d generations per tick accepted", n)
+		}
+	}
+	if err := mv.SetGenerationsPerTick(404, 2); !errors.Is(err, ErrUniverseNotFound) {
+		t.Errorf("speeding up a missing universe: %v, want ErrUniverseNotFound", err)
+	}
+	if err := mv.SetGenerationsPerTick(ids[0], 3); err != nil {
+		t.Fatal(err)
+	}
+	mv.Evolve(cfg)
+	mv.Evolve(cfg)
+	if got := generations(mv); !reflect.DeepEqual(got, []int{6, 2}) {
+		t.Errorf("generations %v, want [6 2]", got)
+	}
+	// Steps advance by generations, whatever the speed.
+	if err := mv.Step(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := generations(mv); !reflect.DeepEqual(got, []int{7, 3}) {
+		t.Errorf("stepped to generations %v, want [7 3]", got)
+	}
+	for i, u := range streamed(t, mv) {
+		if want := []int{3, 1}[i]; u.GenerationsPerTick != want {
+			t.Errorf("universe #%d streamed %d generations per tick, want %d", u.ID, u.GenerationsPerTick, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package multiverse
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ride90/game-of-life/internal/universe"
+)
+
+const (
+	// MaxSteps limits the number of generations a single step advances universes by.
+	MaxSteps = 1000
+	// DefaultFps is the tick rate until another one is set.
+	DefaultFps = 12
+	// MaxFps limits the tick rate.
+	MaxFps = 120
+	// MaxGenerationsPerTick limits the speed of a universe.
+	MaxGenerationsPerTick = 100
+)
+
+// Simulation describes how universes are evolved
+type Simulation struct {
+	Paused bool `json:"paused"`
+	// Fps is the number of ticks per second, universes evolve & get streamed every tick.
+	Fps int `json:"fps"`
+}
+
+// SimulationPatch holds changes to the simulation, nil fields are left untouched
+type SimulationPatch struct {
+	Paused *bool `json:"paused"`
+	Fps    *int  `json:"fps"`
+}
+
+// control describes how a single universe is evolved
+type control struct {
+	paused bool
+	// generationsPerTick is the number of generations the universe evolves by every tick.
+	generationsPerTick int
+}
+
+// defaultControl is used for universes without a control of their own.
+var defaultControl = control{generationsPerTick: 1}
+
+// Simulation returns the state of the simulation
+func (r *Multiverse) Simulation() Simulation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return Simulation{Paused: r.paused, Fps: r.fps}
+}
+
+// UpdateSimulation changes the simulation, nothing is changed on error
+func (r *Multiverse) UpdateSimulation(patch SimulationPatch) error {
+	if patch.Fps != nil && (*patch.Fps < 1 || *patch.Fps > MaxFps) {
+		return fmt.Errorf("fps must be between 1 and %d, got %d", MaxFps, *patch.Fps)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if patch.Paused != nil {
+		r.paused = *patch.Paused
+	}
+	if patch.Fps != nil && *patch.Fps != r.fps {
+		r.fps = *patch.Fps
+		for _, c := range r.fpsWatchers {
+			// Only the latest rate matters, one not received yet is replaced.
+			select {
+			case <-c:
+			default:
+			}
+			c <- r.fps
+		}
+	}
+	return nil
+}
+
+// WatchFps returns a channel receiving the tick rate whenever it changes
+// Tickers are reset at once this way, not only after a tick of the old rate.
+func (r *Multiverse) WatchFps() <-chan int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c := make(chan int, 1)
+	r.fpsWatchers = append(r.fpsWatchers, c)
+	return c
+}
+
+// Pause freezes all universes until the simulation is resumed, they can still be stepped
+func (r *Multiverse) Pause() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.paused = true
+}
+
+// Resume lets universes evolve again, universes paused on their own stay paused
+func (r *Multiverse) Resume() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.paused = false
+}
+
+// Step evolves universes which aren't paused on their own by the given number of generations
+// It's meant for a paused simulation, universes aren't removed when they settle.
+func (r *Multiverse) Step(generations int) error {
+	if err := checkSteps(generations); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.evolve(r.running(), generations, false)
+	return nil
+}
+
+// PauseUniverse freezes the universe with the given ID until it's resumed
+func (r *Multiverse) PauseUniverse(id uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, err := r.controlOf(id)
+	if err != nil {
+		return err
+	}
+	c.paused = true
+	return nil
+}
+
+// ResumeUniverse lets the universe with the given ID evolve again, unless the simulation is paused
+func (r *Multiverse) ResumeUniverse(id uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, err := r.controlOf(id)
+	if err != nil {
+		return err
+	}
+	c.paused = false
+	return nil
+}
+
+// StepUniverse evolves the universe with the given ID by the given number of generations
+func (r *Multiverse) StepUniverse(id uint64, generations int) error {
+	if err := checkSteps(generations); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	i, err := r.find(id)
+	if err != nil {
+		return err
+	}
+	r.evolve(r.universes[i:i+1], generations, false)
+	return nil
+}
+
+// SetGenerationsPerTick sets the number of generations the universe with the given ID evolves by every tick
+func (r *Multiverse) SetGenerationsPerTick(id uint64, generations int) error {
+	if err := CheckGenerationsPerTick(generations); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, err := r.controlOf(id)
+	if err != nil {
+		return err
+	}
+	c.generationsPerTick = generations
+	return nil
+}
+
+// CheckGenerationsPerTick ensures the speed of a universe is within limits
+func CheckGenerationsPerTick(generations int) error {
+	if generations < 1 || generations > MaxGenerationsPerTick {
+		return fmt.Errorf(
+			"generations per tick must be between 1 and %d, got %d", MaxGenerationsPerTick, generations,
+		)
+	}
+	return nil
+}
+
+// checkSteps ensures a step is within limits
+func checkSteps(generations int) error {
+	if generations < 1 || generations > MaxSteps {
+		return fmt.Errorf("steps must be between 1 and %d, got %d", MaxSteps, generations)
+	}
+	return nil
+}
+
+// controlOf returns the control of the universe with the given ID, creating it if needed
+// Must be called with the lock held.
+func (r *Multiverse) controlOf(id uint64) (*control, error) {
+	if _, err := r.find(id); err != nil {
+		return nil, err
+	}
+	c, ok := r.controls[id]
+	if !ok {
+		c = &control{}
+		*c = defaultControl
+		r.controls[id] = c
+	}
+	return c, nil
+}
+
+// control returns the control of the universe with the given ID
+// Must be called with the lock held.
+func (r *Multiverse) control(id uint64) control {
+	if c, ok := r.controls[id]; ok {
+		return *c
+	}
+	return defaultControl
+}
+
+// running returns universes which aren't paused on their own
+// Must be called with the lock held.
+func (r *Multiverse) running() []universe.Automaton {
+	if len(r.controls) == 0 {
+		return r.universes
+	}
+	running := make([]universe.Automaton, 0, len(r.universes))
+	for _, u := range r.universes {
+		if !r.control(u.Stats().ID).paused {
+			running = append(running, u)
+		}
+	}
+	return running
+}
+
+// forgetControls drops controls of universes which are gone
+// Must be called with the lock held.
+func (r *Multiverse) forgetControls() {
+	for id := range r.controls {
+		if _, err := r.find(id); err != nil {
+			delete(r.controls, id)
+		}
+	}
+}
+
+// encode returns the JSON representation of a universe with its simulation state
+// Must be called with the lock held.
+func (r *Multiverse) encode(u universe.Automaton) ([]byte, error) {
+	data, err := u.Encode()
+	if err != nil {
+		return nil, err
+	}
+	c := r.control(u.Stats().ID)
+	state, err := json.Marshal(struct {
+		Paused             bool `json:"paused"`
+		GenerationsPerTick int  `json:"generations_per_tick"`
+	}{r.paused || c.paused, c.generationsPerTick})
+	if err != nil {
+		return nil, err
+	}
+	// Both are JSON objects, fields of the state go first.
+	return append(append(state[:len(state)-1], ','), data[1:]...), nil
+}
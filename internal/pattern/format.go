@@ -0,0 +1,57 @@
+package pattern
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format describes a pattern file format
+type Format struct {
+	Name        string
+	Extension   string
+	ContentType string
+	Encode      func(p *Pattern) string
+	Decode      func(data string) (*Pattern, error)
+}
+
+// formats lists supported import & export formats by name.
+var formats = map[string]Format{
+	"rle":     {"rle", "rle", "application/x-rle", EncodeRLE, DecodeRLE},
+	"life106": {"life106", "lif", "text/plain", EncodeLife106, DecodeLife106},
+	"cells":   {"cells", "cells", "text/plain", EncodePlaintext, DecodeCells},
+	"mc":      {"mc", "mc", "text/plain", EncodeMacrocell, DecodeMacrocell},
+}
+
+// Decode decodes a pattern in any supported format, recognised by its first line
+// Macrocell & Life 1.06 files start with their headers, plaintext ones with
+// '!' comments or cells, anything else is taken for RLE.
+func Decode(data string) (*Pattern, error) {
+	first := strings.TrimSpace(data)
+	if i := strings.IndexByte(first, '\n'); i >= 0 {
+		first = strings.TrimSpace(first[:i])
+	}
+	switch {
+	case strings.HasPrefix(first, "[M2]"):
+		return DecodeMacrocell(data)
+	case strings.HasPrefix(first, "#Life 1.06"):
+		return DecodeLife106(data)
+	case strings.HasPrefix(first, "!") || strings.Trim(first, ".O*") == "":
+		return DecodeCells(data)
+	}
+	return DecodeRLE(data)
+}
+
+// ParseFormat finds a format by its name
+func ParseFormat(name string) (Format, error) {
+	f, ok := formats[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		names := make([]string, 0, len(formats))
+		for n := range formats {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Format{}, fmt.Errorf("unknown format %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return f, nil
+}
@@ -0,0 +1,82 @@
+package pattern
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EncodeLife106 encodes a pattern in the Life 1.06 format
+// Every alive cell is written as "x y" relative to the top left corner,
+// neither the size nor the rule is kept.
+func EncodeLife106(p *Pattern) string {
+	var sb strings.Builder
+	sb.WriteString("#Life 1.06\n")
+	for y, row := range p.Cells {
+		for x, alive := range row {
+			if alive {
+				fmt.Fprintf(&sb, "%d %d\n", x, y)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// DecodeLife106 decodes a pattern in the Life 1.06 format
+// Cells at negative coordinates shift the pattern, so it starts at the top left
+// corner or the leftmost & topmost alive cells, whichever is further out.
+//
+//	#Life 1.06
+//	1 0
+//	2 1
+//	0 2
+func DecodeLife106(data string) (*Pattern, error) {
+	var cells [][2]int
+	minX, minY, maxX, maxY := 0, 0, 0, 0
+	for i, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("life106: line %d: expected \"x y\", got %q", i+1, line)
+		}
+		x, errX := strconv.Atoi(fields[0])
+		y, errY := strconv.Atoi(fields[1])
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("life106: line %d: coordinates must be integers, got %q", i+1, line)
+		}
+		if x < -MaxCells || x >= MaxCells || y < -MaxCells || y >= MaxCells {
+			return nil, fmt.Errorf("life106: line %d: cell (%d, %d) is too far away", i+1, x, y)
+		}
+		if len(cells) == 0 {
+			maxX, maxY = x, y
+		}
+		if x < minX {
+			minX = x
+		}
+		if x > maxX {
+			maxX = x
+		}
+		if y < minY {
+			minY = y
+		}
+		if y > maxY {
+			maxY = y
+		}
+		cells = append(cells, [2]int{x, y})
+	}
+	if len(cells) == 0 {
+		return &Pattern{Width: 1, Height: 1, Cells: newMatrix(1, 1)}, nil
+	}
+	p := &Pattern{Width: maxX - minX + 1, Height: maxY - minY + 1}
+	if p.Width*p.Height > MaxCells {
+		return nil, fmt.Errorf("life106: pattern of %dx%d cells exceeds the limit of %d cells", p.Width, p.Height, MaxCells)
+	}
+	p.Cells = newMatrix(p.Width, p.Height)
+	for _, c := range cells {
+		p.Cells[c[1]-minY][c[0]-minX] = true
+	}
+	return p, nil
+}
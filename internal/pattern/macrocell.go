@@ -0,0 +1,273 @@
+package pattern
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// macrocellLeafLevel is the level of 8x8 leaves of the macrocell quadtree.
+const macrocellLeafLevel = 3
+
+// macrocellEncoder writes quadtree nodes, each distinct node once
+type macrocellEncoder struct {
+	p     *Pattern
+	sb    strings.Builder
+	count int
+	// leaves & nodes map already written nodes to their 1-based line numbers.
+	leaves map[string]int
+	nodes  map[[5]int]int
+}
+
+// EncodeMacrocell encodes a pattern in Golly's macrocell format
+// The pattern becomes the top left corner of a quadtree of identical subtrees
+// written once, the rule is kept, the size is not.
+func EncodeMacrocell(p *Pattern) string {
+	e := &macrocellEncoder{p: p, leaves: map[string]int{}, nodes: map[[5]int]int{}}
+	e.sb.WriteString("[M2] (game-of-life)\n")
+	if p.Rule != "" {
+		fmt.Fprintf(&e.sb, "#R %s\n", p.Rule)
+	}
+
+	level := macrocellLeafLevel
+	for 1<<level < p.Width || 1<<level < p.Height {
+		level++
+	}
+	if e.node(0, 0, level) == 0 {
+		// An empty pattern still needs a root.
+		e.sb.WriteString("$$$$$$$$\n")
+	}
+	return e.sb.String()
+}
+
+// node writes the node of the given level at (x, y) and returns its line number
+// 0 stands for an empty node, which is never written.
+func (e *macrocellEncoder) node(x, y, level int) int {
+	if x >= e.p.Width || y >= e.p.Height {
+		return 0
+	}
+	if level == macrocellLeafLevel {
+		return e.leaf(x, y)
+	}
+	half := 1 << (level - 1)
+	key := [5]int{
+		level,
+		e.node(x, y, level-1),
+		e.node(x+half, y, level-1),
+		e.node(x, y+half, level-1),
+		e.node(x+half, y+half, level-1),
+	}
+	if key[1]|key[2]|key[3]|key[4] == 0 {
+		return 0
+	}
+	if index, ok := e.nodes[key]; ok {
+		return index
+	}
+	fmt.Fprintf(&e.sb, "%d %d %d %d %d\n", key[0], key[1], key[2], key[3], key[4])
+	e.count++
+	e.nodes[key] = e.count
+	return e.count
+}
+
+// leaf writes the 8x8 leaf at (x, y) as rows of '.' & '*' ended by '$'
+func (e *macrocellEncoder) leaf(x, y int) int {
+	var sb strings.Builder
+	empty := true
+	for dy := 0; dy < 1<<macrocellLeafLevel; dy++ {
+		var row []byte
+		for dx := 0; dx < 1<<macrocellLeafLevel; dx++ {
+			if y+dy < e.p.Height && x+dx < e.p.Width && e.p.Cells[y+dy][x+dx] {
+				for len(row) < dx {
+					row = append(row, '.')
+				}
+				row = append(row, '*')
+				empty = false
+			}
+		}
+		sb.Write(row)
+		sb.WriteByte('$')
+	}
+	if empty {
+		return 0
+	}
+	line := sb.String()
+	if index, ok := e.leaves[line]; ok {
+		return index
+	}
+	e.sb.WriteString(line)
+	e.sb.WriteByte('\n')
+	e.count++
+	e.leaves[line] = e.count
+	return e.count
+}
+
+// macrocellNode is a decoded quadtree node, leaves hold their alive cells
+type macrocellNode struct {
+	level    int
+	children [4]int // nw, ne, sw & se line numbers, 0 for empty nodes
+	cells    [][2]int
+	// width & height span alive cells from the node's top left corner, 0 for empty nodes.
+	width, height int
+	measured      bool
+}
+
+// DecodeMacrocell decodes a pattern in Golly's two-state macrocell format
+// The last node is the root, its top left corner becomes the pattern's one & the
+// size spans alive cells from there. The rule is taken from a "#R" line.
+//
+//	[M2] (golly 4.2)
+//	#R B3/S23
+//	.*$..*$***$$$$$$
+func DecodeMacrocell(data string) (*Pattern, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	if !strings.HasPrefix(strings.TrimSpace(lines[0]), "[M2]") {
+		return nil, fmt.Errorf("mc: missing header line \"[M2]\"")
+	}
+	p := &Pattern{}
+	nodes := []*macrocellNode{nil}
+	for i, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		position := func() string {
+			return fmt.Sprintf("mc: line %d", i+2)
+		}
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#R"):
+			p.Rule = strings.TrimSpace(line[2:])
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		n := &macrocellNode{level: macrocellLeafLevel}
+		if c := line[0]; c == '.' || c == '*' || c == '$' {
+			x, y := 0, 0
+			for _, c := range line {
+				switch {
+				case c == '$':
+					x, y = 0, y+1
+				case x >= 1<<macrocellLeafLevel || y >= 1<<macrocellLeafLevel:
+					return nil, fmt.Errorf("%s: leaf is larger than 8x8 cells", position())
+				case c == '.':
+					x++
+				case c == '*':
+					n.cells = append(n.cells, [2]int{x, y})
+					x++
+				default:
+					return nil, fmt.Errorf("%s: unexpected character %q in a leaf, expected ., * or $", position(), c)
+				}
+			}
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) != 5 {
+				return nil, fmt.Errorf("%s: expected \"level nw ne sw se\", got %q", position(), line)
+			}
+			var values [5]int
+			for j, field := range fields {
+				v, err := strconv.Atoi(field)
+				if err != nil || v < 0 {
+					return nil, fmt.Errorf("%s: expected non-negative integers, got %q", position(), line)
+				}
+				values[j] = v
+			}
+			n.level = values[0]
+			if n.level <= macrocellLeafLevel || n.level > macrocellMaxLevel {
+				return nil, fmt.Errorf(
+					"%s: node level must be between %d and %d, got %d",
+					position(), macrocellLeafLevel+1, macrocellMaxLevel, n.level,
+				)
+			}
+			copy(n.children[:], values[1:])
+			for _, child := range n.children {
+				if child >= len(nodes) {
+					return nil, fmt.Errorf("%s: node %d isn't defined yet", position(), child)
+				}
+				if child != 0 && nodes[child].level != n.level-1 {
+					return nil, fmt.Errorf("%s: node %d isn't of level %d", position(), child, n.level-1)
+				}
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 1 {
+		return nil, fmt.Errorf("mc: no nodes")
+	}
+
+	root := len(nodes) - 1
+	measureMacrocell(nodes, root)
+	p.Width, p.Height = nodes[root].width, nodes[root].height
+	if p.Width == 0 {
+		// An empty pattern.
+		p.Width, p.Height = 1, 1
+	}
+	if p.Width > MaxCells || p.Height > MaxCells || p.Width*p.Height > MaxCells {
+		return nil, fmt.Errorf("mc: pattern of %dx%d cells exceeds the limit of %d cells", p.Width, p.Height, MaxCells)
+	}
+	p.Cells = newMatrix(p.Width, p.Height)
+	fillMacrocell(p, nodes, root, 0, 0)
+	return p, nil
+}
+
+// macrocellMaxLevel is the level of the largest decoded node, far beyond MaxCells.
+const macrocellMaxLevel = 62
+
+// measureMacrocell finds the extent of alive cells of the node & its children
+// Each distinct node is measured once, shared subtrees cost nothing.
+func measureMacrocell(nodes []*macrocellNode, index int) {
+	n := nodes[index]
+	if n.measured {
+		return
+	}
+	n.measured = true
+	if n.level == macrocellLeafLevel {
+		for _, c := range n.cells {
+			if c[0]+1 > n.width {
+				n.width = c[0] + 1
+			}
+			if c[1]+1 > n.height {
+				n.height = c[1] + 1
+			}
+		}
+		return
+	}
+	half := 1 << (n.level - 1)
+	for i, child := range n.children {
+		if child == 0 {
+			continue
+		}
+		measureMacrocell(nodes, child)
+		c := nodes[child]
+		if c.width == 0 {
+			continue
+		}
+		// Children are nw, ne, sw & se.
+		width, height := c.width+i%2*half, c.height+i/2*half
+		if width > n.width {
+			n.width = width
+		}
+		if height > n.height {
+			n.height = height
+		}
+	}
+}
+
+// fillMacrocell copies alive cells of the measured node at (x, y) into the pattern
+func fillMacrocell(p *Pattern, nodes []*macrocellNode, index, x, y int) {
+	n := nodes[index]
+	if n.width == 0 {
+		return
+	}
+	if n.level == macrocellLeafLevel {
+		for _, c := range n.cells {
+			p.Cells[y+c[1]][x+c[0]] = true
+		}
+		return
+	}
+	half := 1 << (n.level - 1)
+	for i, child := range n.children {
+		if child != 0 {
+			fillMacrocell(p, nodes, child, x+i%2*half, y+i/2*half)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pattern
+
+import (
+	"fmt"
+)
+
+// MaxCells limits the size of a decoded pattern.
+const MaxCells = 1 << 24
+
+// Pattern represents a rectangular matrix of cells read from a pattern file
+type Pattern struct {
+	Width  int
+	Height int
+	Cells  [][]bool // rows first
+	Rule   string   // as written in the file, empty if not given
+}
+
+// Place copies the pattern into a new width x height matrix
+// Without an offset the pattern is centred.
+func (p *Pattern) Place(width, height int, offsetX, offsetY *int) ([][]bool, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("universe size must be positive, got %dx%d", width, height)
+	}
+	if width*height > MaxCells {
+		return nil, fmt.Errorf("universe of %dx%d cells exceeds the limit of %d cells", width, height, MaxCells)
+	}
+	x, y := (width-p.Width)/2, (height-p.Height)/2
+	if offsetX != nil {
+		x = *offsetX
+	}
+	if offsetY != nil {
+		y = *offsetY
+	}
+	if x < 0 || y < 0 || x+p.Width > width || y+p.Height > height {
+		return nil, fmt.Errorf(
+			"pattern of %dx%d cells at (%d, %d) doesn't fit into %dx%d universe",
+			p.Width, p.Height, x, y, width, height,
+		)
+	}
+	matrix := newMatrix(width, height)
+	for py := range p.Cells {
+		copy(matrix[y+py][x:], p.Cells[py])
+	}
+	return matrix, nil
+}
+
+// newMatrix allocates a matrix of dead cells
+func newMatrix(width, height int) [][]bool {
+	matrix := make([][]bool, height)
+	for y := range matrix {
+		matrix[y] = make([]bool, width)
+	}
+	return matrix
+}
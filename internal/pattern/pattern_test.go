@@ -0,0 +1,189 @@
+package pattern
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// parsePattern creates a pattern from rows of cells, "o" is alive
+func parsePattern(rule string, rows ...string) *Pattern {
+	p := &Pattern{Width: len(rows[0]), Height: len(rows), Rule: rule}
+	p.Cells = newMatrix(p.Width, p.Height)
+	for y, row := range rows {
+		for x, c := range row {
+			p.Cells[y][x] = c == 'o'
+		}
+	}
+	return p
+}
+
+// aliveCells lists coordinates of alive cells of a pattern
+func aliveCells(p *Pattern) map[[2]int]bool {
+	cells := map[[2]int]bool{}
+	for y, row := range p.Cells {
+		for x, alive := range row {
+			if alive {
+				cells[[2]int{x, y}] = true
+			}
+		}
+	}
+	return cells
+}
+
+func TestRoundTrip(t *testing.T) {
+	patterns := map[string]*Pattern{
+		"empty":      parsePattern("B3/S23", "...", "...", "..."),
+		"1x1":        parsePattern("B3/S23", "o"),
+		"1x1 dead":   parsePattern("", "."),
+		"glider":     parsePattern("B3/S23", ".o.", "..o", "ooo"),
+		"non-square": parsePattern("B36/S23", "o.........o", "...........", "....o.o....", "o.........."),
+		"margins":    parsePattern("B3/S23", "......", "..oo..", "..oo..", "......"),
+		"tall":       parsePattern("B2/S/3", "o", ".", ".", "o", "o", ".", ".", ".", ".", "o", "."),
+		// Spans several macrocell leaves, the middle one is repeated.
+		"leaves": parsePattern(
+			"B3/S23",
+			"o......o.......o.......o",
+			".o......o.......o.......",
+			"........................",
+			"........................",
+			"........................",
+			"........................",
+			"........................",
+			"........................",
+			"........o...............",
+		),
+	}
+	tests := []struct {
+		format string
+		// keepsSize & keepsRule tell what survives, cells always do.
+		keepsSize bool
+		keepsRule bool
+	}{
+		{"rle", true, true},
+		{"life106", false, false},
+		{"cells", true, false},
+		{"mc", false, true},
+	}
+	for _, tt := range tests {
+		format, err := ParseFormat(tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, p := range patterns {
+			data := format.Encode(p)
+			got, err := format.Decode(data)
+			if err != nil {
+				t.Errorf("%s %s: decoding %q failed: %s", tt.format, name, data, err)
+				continue
+			}
+			detected, err := Decode(data)
+			if err != nil {
+				t.Errorf("%s %s: detecting the format of %q failed: %s", tt.format, name, data, err)
+				continue
+			}
+			if EncodeRLE(detected) != EncodeRLE(got) {
+				t.Errorf("%s %s: %q decoded differently when the format was detected", tt.format, name, data)
+			}
+			if len(got.Cells) != got.Height || got.Height == 0 || len(got.Cells[0]) != got.Width || got.Width == 0 {
+				t.Errorf("%s %s: decoded %dx%d pattern holds %d rows", tt.format, name, got.Width, got.Height, len(got.Cells))
+				continue
+			}
+			if tt.keepsSize && (got.Width != p.Width || got.Height != p.Height) {
+				t.Errorf("%s %s: size %dx%d, want %dx%d", tt.format, name, got.Width, got.Height, p.Width, p.Height)
+			}
+			if tt.keepsRule && got.Rule != p.Rule {
+				t.Errorf("%s %s: rule %q, want %q", tt.format, name, got.Rule, p.Rule)
+			}
+			want, alive := aliveCells(p), aliveCells(got)
+			if len(alive) != len(want) {
+				t.Errorf("%s %s: %d alive cells, want %d", tt.format, name, len(alive), len(want))
+				continue
+			}
+			for c := range want {
+				if !alive[c] {
+					t.Errorf("%s %s: cell %v isn't alive", tt.format, name, c)
+				}
+			}
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *Pattern
+	}{
+		{"rle", "#N Blinker\nx = 3, y = 1, rule = B3/S23\n3o!", parsePattern("B3/S23", "ooo")},
+		{"life106 negative", "#Life 1.06\n-1 0\n0 0\n1 0\n", parsePattern("", "ooo")},
+		{"life106 offset", "#Life 1.06\n2 1\n", parsePattern("", "...", "..o")},
+		{"life106 empty", "#Life 1.06\n", parsePattern("", ".")},
+		{"cells", "!Name: Blinker\n!\nOOO\n", parsePattern("", "ooo")},
+		{"cells ragged", ".*\n\n*\n", parsePattern("", ".o", "..", "o.")},
+		{"cells no comment", "O.O\n", parsePattern("", "o.o")},
+		{"mc", "[M2] (golly 4.2)\n#R B36/S23\n.*$..*$***$$$$$$\n", parsePattern("B36/S23", ".o.", "..o", "ooo")},
+		{
+			"mc nodes",
+			"[M2]\n*$\n4 0 1 0 1\n",
+			parsePattern("", "........o", ".........", ".........", ".........", ".........", ".........", ".........", ".........", "........o"),
+		},
+		{"mc empty", "[M2]\n$$$$$$$$\n", parsePattern("", ".")},
+	}
+	for _, tt := range tests {
+		got, err := Decode(tt.data)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if got.Rule != tt.want.Rule || EncodePlaintext(got) != EncodePlaintext(tt.want) {
+			t.Errorf(
+				"%s: decoded %q, rule %q, want %q, rule %q",
+				tt.name, EncodePlaintext(got), got.Rule, EncodePlaintext(tt.want), tt.want.Rule,
+			)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+	}{
+		{"rle", "bo$2bo$3o!"},
+		{"rle", "x = 3, y = 3\nbo$2bo$3o"},
+		{"life106", "#Life 1.06\n1\n"},
+		{"life106", "#Life 1.06\na b\n"},
+		{"life106", "#Life 1.06\n0 0\n100000 100000\n"},
+		{"cells", "!only comments\n"},
+		{"cells", "OXO\n"},
+		{"mc", "*$\n"},
+		{"mc", "[M2]\n"},
+		{"mc", "[M2]\n*********$\n"},
+		{"mc", "[M2]\n*$\n4 0 2 0 0\n"},
+		{"mc", "[M2]\n*$\n5 0 1 0 0\n"},
+		{"mc", "[M2]\n*$\n4 1 1 1\n"},
+		// 2^40 cells wide.
+		{"mc", "[M2]\n*$\n4 1 1 0 0\n" + levels(5, 40)},
+	}
+	for _, tt := range tests {
+		format, err := ParseFormat(tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err := format.Decode(tt.data); err == nil {
+			t.Errorf("%s: decoded %q into %dx%d pattern, want an error", tt.format, tt.data, p.Width, p.Height)
+		}
+	}
+}
+
+// levels writes macrocell nodes from the given level up to the last one, each
+// with the previous node in its nw & ne quadrants
+func levels(from, to int) string {
+	var sb strings.Builder
+	for level := from; level <= to; level++ {
+		line := level - from + 2
+		fmt.Fprintf(&sb, "%d %d %d 0 0\n", level, line, line)
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package pattern
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EncodePlaintext encodes a pattern in the plaintext (.cells) format
+// Rows are written in full, so the size is kept, the rule is not.
+func EncodePlaintext(p *Pattern) string {
+	var sb strings.Builder
+	sb.Grow((p.Width + 1) * p.Height)
+	for _, row := range p.Cells {
+		for _, alive := range row {
+			if alive {
+				sb.WriteByte('O')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// DecodeCells decodes a pattern in the plaintext (.cells) format
+// Lines starting with '!' are comments, 'O' or '*' is alive & '.' dead. Rows may
+// omit trailing dead cells, the widest row gives the width.
+//
+//	!Name: Glider
+//	.O
+//	..O
+//	OOO
+func DecodeCells(data string) (*Pattern, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	var rows []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		rows = append(rows, strings.TrimRight(line, " \t"))
+	}
+	// Trailing blank lines aren't rows.
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("cells: no rows of cells")
+	}
+
+	p := &Pattern{Height: len(rows)}
+	for _, row := range rows {
+		if len(row) > p.Width {
+			p.Width = len(row)
+		}
+	}
+	if p.Width == 0 {
+		p.Width = 1
+	}
+	if p.Width*p.Height > MaxCells {
+		return nil, fmt.Errorf("cells: pattern of %dx%d cells exceeds the limit of %d cells", p.Width, p.Height, MaxCells)
+	}
+	p.Cells = newMatrix(p.Width, p.Height)
+	for y, row := range rows {
+		for x, c := range row {
+			switch c {
+			case 'O', '*':
+				p.Cells[y][x] = true
+			case '.':
+			default:
+				return nil, fmt.Errorf("cells: row %d, column %d: unexpected character %q, expected O, * or .", y+1, x+1, c)
+			}
+		}
+	}
+	return p, nil
+}
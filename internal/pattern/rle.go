@@ -0,0 +1,201 @@
+package pattern
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DecodeRLE decodes a pattern in Golly's run length encoded format
+//
+//	#N Glider
+//	x = 3, y = 3, rule = B3/S23
+//	bob$2bo$3o!
+func DecodeRLE(data string) (*Pattern, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	p := &Pattern{}
+
+	// Skip comments & find the header.
+	lineIndex := 0
+	for ; lineIndex < len(lines); lineIndex++ {
+		line := strings.TrimSpace(lines[lineIndex])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		break
+	}
+	if lineIndex == len(lines) {
+		return nil, fmt.Errorf("rle: missing header line \"x = <width>, y = <height>\"")
+	}
+	if err := p.parseHeader(lines[lineIndex]); err != nil {
+		return nil, fmt.Errorf("rle: line %d: %w", lineIndex+1, err)
+	}
+	p.Cells = newMatrix(p.Width, p.Height)
+
+	// Decode the body.
+	x, y, count := 0, 0, 0
+	for lineIndex++; lineIndex < len(lines); lineIndex++ {
+		line := lines[lineIndex]
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for i, c := range line {
+			position := func() string {
+				return fmt.Sprintf("rle: line %d, column %d", lineIndex+1, i+1)
+			}
+			switch {
+			case c >= '0' && c <= '9':
+				count = count*10 + int(c-'0')
+				if count > p.Width*p.Height+p.Height {
+					return nil, fmt.Errorf("%s: run count %d exceeds the pattern size", position(), count)
+				}
+				continue
+			case c == ' ' || c == '\t':
+				if count > 0 {
+					return nil, fmt.Errorf("%s: whitespace within a run count", position())
+				}
+				continue
+			}
+			run := count
+			if run == 0 {
+				run = 1
+			}
+			count = 0
+			switch c {
+			case 'b', '.':
+				x += run
+			case 'o', 'A':
+				if y >= p.Height {
+					return nil, fmt.Errorf("%s: row %d is beyond the height y = %d", position(), y+1, p.Height)
+				}
+				if x+run > p.Width {
+					return nil, fmt.Errorf("%s: row %d is wider than x = %d", position(), y+1, p.Width)
+				}
+				for ; run > 0; run-- {
+					p.Cells[y][x] = true
+					x++
+				}
+			case '$':
+				y += run
+				x = 0
+			case '!':
+				return p, nil
+			default:
+				return nil, fmt.Errorf("%s: unexpected character %q, expected b, o, $, ! or a run count", position(), c)
+			}
+			if x > p.Width {
+				return nil, fmt.Errorf("%s: row %d is wider than x = %d", position(), y+1, p.Width)
+			}
+		}
+	}
+	return nil, fmt.Errorf("rle: missing terminating '!'")
+}
+
+// parseHeader parses the "x = 3, y = 3, rule = B3/S23" line
+func (p *Pattern) parseHeader(line string) error {
+	seen := map[string]bool{}
+	lastKey := ""
+	for _, field := range strings.Split(line, ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok && lastKey == "rule" {
+			// Bounded grid suffixes contain commas, e.g. "rule = B3/S23:T10,10".
+			p.Rule += "," + strings.TrimSpace(field)
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("invalid header field %q, expected \"key = value\"", strings.TrimSpace(field))
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if seen[key] {
+			return fmt.Errorf("duplicated header field %q", key)
+		}
+		seen[key] = true
+		lastKey = key
+		switch key {
+		case "x", "y":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("header field %s must be a positive integer, got %q", key, value)
+			}
+			if key == "x" {
+				p.Width = n
+			} else {
+				p.Height = n
+			}
+		case "rule":
+			p.Rule = value
+		default:
+			return fmt.Errorf("unknown header field %q", key)
+		}
+	}
+	if !seen["x"] || !seen["y"] {
+		return fmt.Errorf("header must define both x and y")
+	}
+	if p.Width*p.Height > MaxCells {
+		return fmt.Errorf("pattern of %dx%d cells exceeds the limit of %d cells", p.Width, p.Height, MaxCells)
+	}
+	return nil
+}
+
+// rleLineLength is the maximum length of an encoded RLE body line.
+const rleLineLength = 70
+
+// EncodeRLE encodes a pattern in Golly's run length encoded format
+// Trailing dead cells of rows & trailing empty rows are omitted, the header
+// keeps the full size.
+func EncodeRLE(p *Pattern) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "x = %d, y = %d", p.Width, p.Height)
+	if p.Rule != "" {
+		fmt.Fprintf(&sb, ", rule = %s", p.Rule)
+	}
+	sb.WriteByte('\n')
+
+	lineLength := 0
+	write := func(count int, tag byte) {
+		token := string(tag)
+		if count > 1 {
+			token = strconv.Itoa(count) + token
+		}
+		if lineLength+len(token) > rleLineLength {
+			sb.WriteByte('\n')
+			lineLength = 0
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+
+	emptyRows := 0
+	for y, row := range p.Cells {
+		end := len(row)
+		for end > 0 && !row[end-1] {
+			end--
+		}
+		if end == 0 {
+			emptyRows++
+			continue
+		}
+		if y > emptyRows {
+			// The previous non-empty row ends here.
+			write(emptyRows+1, '$')
+		} else if emptyRows > 0 {
+			write(emptyRows, '$')
+		}
+		emptyRows = 0
+		for x := 0; x < end; {
+			run := 1
+			for x+run < end && row[x+run] == row[x] {
+				run++
+			}
+			tag := byte('b')
+			if row[x] {
+				tag = 'o'
+			}
+			write(run, tag)
+			x += run
+		}
+	}
+	write(1, '!')
+	sb.WriteByte('\n')
+	return sb.String()
+}
@@ -0,0 +1,191 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ride90/game-of-life/internal/universe"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unidentifiedPrefix starts codes of objects which aren't periodic, they're tallied but never rare.
+const unidentifiedPrefix = "xx_"
+
+// MaxRare is the number of rare objects kept, older ones are dropped first.
+const MaxRare = 1000
+
+// Tally counts objects of the same kind found in all soups
+type Tally struct {
+	Code  string `json:"code"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Rarity is an object rarer than the threshold & the soup it was found in
+type Rarity struct {
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+	Rule string `json:"rule"`
+	// Soup reproduces the object, posted to /api/universe/random with the rule.
+	Soup universe.Soup `json:"soup"`
+	// Frequency is the share of all objects found so far the object makes up.
+	Frequency float64   `json:"frequency"`
+	FoundAt   time.Time `json:"found_at"`
+}
+
+// Results is what a search found so far
+type Results struct {
+	Soups   int       `json:"soups"`
+	Objects int       `json:"objects"`
+	Rare    []Rarity  `json:"rare"`
+	Tallies []Tally   `json:"tallies"`
+	Updated time.Time `json:"updated"`
+}
+
+// Search tallies objects of censused soups & records rare ones, results are
+// persisted to a local file by Flush
+type Search struct {
+	path string
+	// rareFrequency is the share of all objects below which an object is rare.
+	rareFrequency float64
+	results       Results
+	tallies       map[string]int
+	// dirty is set by results not persisted yet.
+	dirty bool
+	lock  sync.Mutex
+}
+
+// New creates a Search persisting to the file at path, results found before are loaded from it
+func New(path string, rareFrequency float64) (*Search, error) {
+	if rareFrequency <= 0 || rareFrequency > 1 {
+		return nil, fmt.Errorf("rare frequency must be between 0 and 1, got %g", rareFrequency)
+	}
+	s := &Search{path: path, rareFrequency: rareFrequency, tallies: map[string]int{}}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &s.results); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, tally := range s.results.Tallies {
+		s.tallies[tally.Code] = i
+	}
+	s.trimRare()
+	return s, nil
+}
+
+// Record tallies objects of a censused soup & returns objects rarer than the threshold
+func (s *Search) Record(soup universe.Soup, rule universe.Rule, census universe.Census) []Rarity {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.results.Soups++
+	for _, entry := range census {
+		i, ok := s.tallies[entry.Code]
+		if !ok {
+			i = len(s.results.Tallies)
+			s.tallies[entry.Code] = i
+			s.results.Tallies = append(s.results.Tallies, Tally{Code: entry.Code, Name: entry.Name})
+		}
+		s.results.Tallies[i].Count += entry.Count
+		s.results.Objects += entry.Count
+	}
+
+	// Frequencies aren't known until enough objects are found.
+	var rare []Rarity
+	if float64(s.results.Objects)*s.rareFrequency >= 1 {
+		now := time.Now().UTC()
+		for _, entry := range census {
+			if strings.HasPrefix(entry.Code, unidentifiedPrefix) {
+				continue
+			}
+			frequency := float64(s.results.Tallies[s.tallies[entry.Code]].Count) / float64(s.results.Objects)
+			if frequency < s.rareFrequency {
+				rare = append(rare, Rarity{
+					Code:      entry.Code,
+					Name:      entry.Name,
+					Rule:      rule.String(),
+					Soup:      soup,
+					Frequency: frequency,
+					FoundAt:   now,
+				})
+			}
+		}
+	}
+	s.results.Rare = append(s.results.Rare, rare...)
+	s.trimRare()
+	s.results.Updated = time.Now().UTC()
+	s.dirty = true
+	return rare
+}
+
+// Flush persists results recorded since the last flush
+func (s *Search) Flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	if err := s.persist(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// trimRare drops the oldest rare objects beyond MaxRare, reusing the array.
+// Must be called with the lock held.
+func (s *Search) trimRare() {
+	if extra := len(s.results.Rare) - MaxRare; extra > 0 {
+		n := copy(s.results.Rare, s.results.Rare[extra:])
+		s.results.Rare = s.results.Rare[:n]
+	}
+}
+
+// Results returns a copy of results, most common objects first
+func (s *Search) Results() Results {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	results := s.results
+	results.Rare = append([]Rarity{}, s.results.Rare...)
+	results.Tallies = append([]Tally{}, s.results.Tallies...)
+	sort.SliceStable(results.Tallies, func(i, j int) bool {
+		return results.Tallies[i].Count > results.Tallies[j].Count
+	})
+	return results
+}
+
+// persist writes results to the file, replacing it at once so it's never half written
+// Must be called with the lock held.
+func (s *Search) persist() error {
+	data, err := json.MarshalIndent(s.results, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"github.com/ride90/game-of-life/internal/universe"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordKeepsLatestRare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.json")
+	s, err := New(path, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every soup has a block & a blinker, which is rare after its first soup.
+	census := universe.Census{{Code: "xs4_33", Count: 3}, {Code: "xp2_7", Count: 1}}
+	for i := 0; i < MaxRare+10; i++ {
+		seed := universe.Seed(i)
+		if rare := s.Record(universe.Soup{Width: 8, Height: 8, Seed: &seed}, universe.ConwayRule, census); len(rare) != 1 {
+			t.Fatalf("soup %d: %d rare objects, want 1", i, len(rare))
+		}
+	}
+	results := s.Results()
+	if len(results.Rare) != MaxRare || *results.Rare[0].Soup.Seed != 10 {
+		t.Errorf("%d rare objects from seed %d, want %d from seed 10", len(results.Rare), *results.Rare[0].Soup.Seed, MaxRare)
+	}
+
+	// Nothing is written until results are flushed.
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("results written before a flush: %v", err)
+	}
+	if err = s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := New(path, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Results(); got.Soups != MaxRare+10 || len(got.Rare) != MaxRare || got.Tallies[0].Count != 3*(MaxRare+10) {
+		t.Errorf("loaded %d soups & %d rare objects", got.Soups, len(got.Rare))
+	}
+}
+
+func TestRecordIgnoresUnidentified(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "search.json"), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An unidentified object is as rare as the blinker but never reported.
+	census := universe.Census{{Code: "xs4_33", Count: 4}, {Code: "xx_5", Count: 1}, {Code: "xp2_7", Count: 1}}
+	seed := universe.Seed(1)
+	rare := s.Record(universe.Soup{Width: 8, Height: 8, Seed: &seed}, universe.ConwayRule, census)
+	if len(rare) != 1 || rare[0].Code != "xp2_7" {
+		t.Errorf("rare objects %v, want the blinker only", rare)
+	}
+	if got := s.Results(); got.Objects != 6 || len(got.Tallies) != 3 || got.Tallies[1].Count != 1 {
+		t.Errorf("unidentified object not tallied: %+v", got.Tallies)
+	}
+}
@@ -0,0 +1,301 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// antDirections lists names of directions the ant may face clockwise & offsets of a step in them.
+var antDirections = [4]struct {
+	name   string
+	offset [2]int
+}{
+	{"up", [2]int{0, -1}},
+	{"right", [2]int{1, 0}},
+	{"down", [2]int{0, 1}},
+	{"left", [2]int{-1, 0}},
+}
+
+// antPalette holds the colour of the ant, black cells take the colour of the universe.
+var antPalette = []string{"#e8412c"}
+
+// Ant is Langton's Ant walking over a grid of white & black cells
+// On a white cell it turns right, on a black one left, then it flips the colour
+// of the cell and steps forward. An ant walking off a bounded plane is gone
+// and leaves a static universe behind.
+type Ant struct {
+	ID       uint64
+	Colour   string
+	Topology Topology
+	// MaxPeriod is the longest period, in ticks, looked for.
+	MaxPeriod   int
+	Period      int
+	PeriodFrom  int
+	SettledFrom time.Time
+	width       int
+	height      int
+	// black holds colours of cells, rows first.
+	black      []bool
+	x          int
+	y          int
+	direction  int
+	gone       bool
+	generation int
+	alive      int
+	detector   *periodDetector
+}
+
+// antJSON is the JSON representation of an Ant
+type antJSON struct {
+	Kind     string `json:"kind"`
+	Colour   string `json:"colour"`
+	Topology string `json:"topology"`
+	// Black cells, when absent a white grid of width x height.
+	Matrix [][]bool     `json:"cells"`
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+	Ant    *antPosition `json:"ant"`
+	// Read only fields, the ant is rendered as a cell in its own colour.
+	Palette    []string `json:"palette"`
+	Colours    [][]int  `json:"colours"`
+	ID         uint64   `json:"id"`
+	Generation int      `json:"generation"`
+	Alive      int      `json:"alive"`
+	IsStatic   bool     `json:"static"`
+	Period     int      `json:"period"`
+	PeriodFrom int      `json:"period_from"`
+	Converged  string   `json:"converged"`
+}
+
+// antPosition is the JSON representation of the position of the ant, nil when gone
+type antPosition struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+}
+
+// NewAnt creates Langton's Ant at (x, y) facing the direction ("up", "right", "down" or "left") over black cells, rows first
+func NewAnt(black [][]bool, x, y int, direction string, colour string, topology Topology) (*Ant, error) {
+	if len(black) == 0 || len(black[0]) == 0 {
+		return nil, fmt.Errorf("cells must not be empty")
+	}
+	if topology == nil {
+		topology = DefaultTopology
+	}
+	if _, ok := topology.(Unbounded); ok {
+		return nil, fmt.Errorf("ant can't walk %q topology", topology.Name())
+	}
+	height, width := len(black), len(black[0])
+	if x < 0 || y < 0 || x >= width || y >= height {
+		return nil, fmt.Errorf("ant (%d, %d) must be within the %dx%d grid", x, y, width, height)
+	}
+	a := &Ant{
+		Colour:    colour,
+		Topology:  topology,
+		MaxPeriod: DefaultMaxPeriod,
+		width:     width,
+		height:    height,
+		black:     make([]bool, width*height),
+		x:         x,
+		y:         y,
+		direction: -1,
+	}
+	for i, d := range antDirections {
+		if strings.EqualFold(strings.TrimSpace(direction), d.name) {
+			a.direction = i
+		}
+	}
+	if direction == "" {
+		a.direction = 0
+	}
+	if a.direction < 0 {
+		return nil, fmt.Errorf("unknown direction %q, expected one of: up, right, down, left", direction)
+	}
+	for y, row := range black {
+		if len(row) != width {
+			return nil, fmt.Errorf("cells row %d has %d cells, expected %d", y, len(row), width)
+		}
+		for x, b := range row {
+			a.black[y*width+x] = b
+			if b {
+				a.alive++
+			}
+		}
+	}
+	return a, nil
+}
+
+// decodeAnt decodes Langton's Ant from its JSON representation
+func decodeAnt(data []byte, maxPeriod int) (Automaton, error) {
+	var u antJSON
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	var topology Topology
+	if u.Topology != "" {
+		var err error
+		if topology, err = ParseTopology(u.Topology); err != nil {
+			return nil, err
+		}
+	}
+	black := u.Matrix
+	if len(black) == 0 {
+		if u.Width <= 0 || u.Height <= 0 {
+			return nil, fmt.Errorf("cells or width & height must be given")
+		}
+		black = make([][]bool, u.Height)
+		for y := range black {
+			black[y] = make([]bool, u.Width)
+		}
+	}
+	// The ant starts in the middle facing up unless told otherwise.
+	pos := antPosition{X: len(black[0]) / 2, Y: len(black) / 2}
+	if u.Ant != nil {
+		pos = *u.Ant
+	}
+	a, err := NewAnt(black, pos.X, pos.Y, pos.Direction, u.Colour, topology)
+	if err != nil {
+		return nil, err
+	}
+	if maxPeriod > 0 {
+		a.MaxPeriod = maxPeriod
+	}
+	return a, nil
+}
+
+// Kind returns the name of the kind
+func (a *Ant) Kind() string {
+	return "ant"
+}
+
+// SetID sets the ID identifying the universe within a multiverse
+func (a *Ant) SetID(id uint64) {
+	a.ID = id
+}
+
+// String returns a string representation of the Ant
+func (a *Ant) String() string {
+	return fmt.Sprintf(
+		"Ant Colour: %s Topology: %s Size: %dx%d Converged: %q Generation %d Black: %d",
+		a.Colour, a.Topology.Name(), a.width, a.height, a.convergence(), a.generation, a.alive,
+	)
+}
+
+// Stats returns the size, the number of black cells & the convergence of the Ant
+func (a *Ant) Stats() Stats {
+	return Stats{
+		ID:          a.ID,
+		Width:       a.width,
+		Height:      a.height,
+		Generation:  a.generation,
+		Alive:       a.alive,
+		Settled:     a.Period > 0,
+		SettledFrom: a.SettledFrom,
+	}
+}
+
+// Hash returns a position dependent hash of black cells & the ant
+func (a *Ant) Hash() uint64 {
+	var gone uint64
+	if a.gone {
+		gone = 1
+	}
+	hash := mixHash(uint64(a.x), uint64(a.y), uint64(a.direction), gone)
+	for i, b := range a.black {
+		if b {
+			hash = mixHash(hash, uint64(i))
+		}
+	}
+	return hash
+}
+
+// Evolve makes the ant turn, flip the colour of its cell & step forward
+func (a *Ant) Evolve() {
+	if a.Period == 0 {
+		a.detectPeriod()
+	}
+	if a.gone {
+		a.generation++
+		return
+	}
+	i := a.y*a.width + a.x
+	if a.black[i] {
+		a.direction = (a.direction + 3) % 4
+		a.alive--
+	} else {
+		a.direction = (a.direction + 1) % 4
+		a.alive++
+	}
+	a.black[i] = !a.black[i]
+	offset := antDirections[a.direction].offset
+	x, y, ok := a.Topology.Resolve(a.x+offset[0], a.y+offset[1], a.width, a.height)
+	a.x, a.y, a.gone = x, y, !ok
+	a.generation++
+}
+
+// detectPeriod looks for the current generation among recent ones
+func (a *Ant) detectPeriod() {
+	if a.detector == nil {
+		a.detector = newPeriodDetector(a.MaxPeriod)
+	}
+	match, ok := a.detector.observe(signature{generation: a.generation, hash: a.Hash()})
+	if !ok {
+		return
+	}
+	a.Period = a.generation - match.generation
+	a.PeriodFrom = match.generation
+	a.SettledFrom = time.Now().UTC()
+	a.detector = nil
+}
+
+// convergence describes what a settled Ant converged to
+func (a *Ant) convergence() string {
+	switch {
+	case a.Period == 0:
+		return ""
+	case a.gone:
+		return "ant gone"
+	default:
+		return fmt.Sprintf("ant looping with period %d", a.Period)
+	}
+}
+
+// Encode returns the JSON representation of the Ant
+// The cell of the ant is rendered alive in the colour of the ant.
+func (a *Ant) Encode() ([]byte, error) {
+	u := antJSON{
+		Kind:       a.Kind(),
+		Colour:     a.Colour,
+		Topology:   a.Topology.Name(),
+		Matrix:     make([][]bool, a.height),
+		Width:      a.width,
+		Height:     a.height,
+		Palette:    antPalette,
+		Colours:    make([][]int, a.height),
+		ID:         a.ID,
+		Generation: a.generation,
+		Alive:      a.alive,
+		IsStatic:   a.gone,
+		Period:     a.Period,
+		PeriodFrom: a.PeriodFrom,
+		Converged:  a.convergence(),
+	}
+	if !a.gone {
+		u.Ant = &antPosition{X: a.x, Y: a.y, Direction: antDirections[a.direction].name}
+	}
+	for y := 0; y < a.height; y++ {
+		u.Matrix[y] = make([]bool, a.width)
+		u.Colours[y] = make([]int, a.width)
+		for x := 0; x < a.width; x++ {
+			u.Matrix[y][x] = a.black[y*a.width+x]
+			u.Colours[y][x] = -1
+		}
+	}
+	if !a.gone {
+		u.Matrix[a.y][a.x] = true
+		u.Colours[a.y][a.x] = 0
+	}
+	return json.Marshal(u)
+}
@@ -0,0 +1,118 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Automaton is a universe of any kind living in a multiverse
+// Life universes are one kind, others (Wireworld, Langton's Ant) evolve by
+// their own laws but settle, get encoded & removed the same way.
+type Automaton interface {
+	fmt.Stringer
+	// Kind returns the name the kind of the automaton is registered under.
+	Kind() string
+	// SetID sets the ID identifying the automaton within a multiverse.
+	SetID(id uint64)
+	// Evolve advances the automaton by a tick.
+	Evolve()
+	// Stats returns the size, the population & the convergence of the automaton.
+	Stats() Stats
+	// Hash returns a position dependent hash of the state of the automaton.
+	Hash() uint64
+	// Encode returns the JSON representation streamed to clients.
+	Encode() ([]byte, error)
+}
+
+// Stats describes an automaton for the multiverse
+type Stats struct {
+	ID         uint64
+	Width      int
+	Height     int
+	Generation int
+	Alive      int
+	// Settled tells if the automaton became static or periodic, SettledFrom
+	// is the time it happened.
+	Settled     bool
+	SettledFrom time.Time
+}
+
+// Cells returns the number of cells of the automaton
+func (s Stats) Cells() int {
+	return s.Width * s.Height
+}
+
+// kindDecoder decodes an automaton of a kind from its JSON representation
+// maxPeriod is the longest period looked for, DefaultMaxPeriod if 0.
+type kindDecoder func(data []byte, maxPeriod int) (Automaton, error)
+
+// DefaultKind is used when no kind is given.
+const DefaultKind = "life"
+
+// kinds holds decoders of all known kinds by name.
+var kinds = map[string]kindDecoder{}
+
+func init() {
+	RegisterKind(DefaultKind, decodeLife)
+	RegisterKind("briansbrain", decodeBriansBrain)
+	RegisterKind("wireworld", decodeWireworld)
+	RegisterKind("ant", decodeAnt)
+}
+
+// RegisterKind makes a kind of automata available by its name
+func RegisterKind(name string, decoder kindDecoder) {
+	kinds[name] = decoder
+}
+
+// Kinds returns names of all known kinds
+func Kinds() []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Decode decodes an automaton of the kind given by the "kind" field, a Life universe by default
+func Decode(data []byte, maxPeriod int) (Automaton, error) {
+	var header struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	kind := strings.ToLower(strings.TrimSpace(header.Kind))
+	if kind == "" {
+		kind = DefaultKind
+	}
+	decode, ok := kinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q, expected one of: %s", kind, strings.Join(Kinds(), ", "))
+	}
+	return decode(data, maxPeriod)
+}
+
+// decodeLife decodes a Life universe
+func decodeLife(data []byte, maxPeriod int) (Automaton, error) {
+	var u Universe
+	if err := u.decode(data, nil, nil); err != nil {
+		return nil, err
+	}
+	u.setMaxPeriod(maxPeriod)
+	return &u, nil
+}
+
+// decodeBriansBrain decodes a Life universe following Brian's Brain rule unless another rule is given
+func decodeBriansBrain(data []byte, maxPeriod int) (Automaton, error) {
+	var u Universe
+	rule := MustParseRule("briansbrain")
+	if err := u.decode(data, &rule, nil); err != nil {
+		return nil, err
+	}
+	u.setMaxPeriod(maxPeriod)
+	return &u, nil
+}
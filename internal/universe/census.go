@@ -0,0 +1,340 @@
+package universe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// wechslerDigits encode 5 cells tall columns of the extended Wechsler format.
+const wechslerDigits = "0123456789abcdefghijklmnopqrstuv"
+
+// knownObjects names common objects, canonical codes are computed on init.
+// Censuses classify 8-connected components, objects made of several, like the
+// pulsar or the aircraft carrier, are never matched & aren't listed.
+var knownObjects = map[string][]string{
+	"block":                  {"OO", "OO"},
+	"beehive":                {".OO.", "O..O", ".OO."},
+	"loaf":                   {".OO.", "O..O", ".O.O", "..O."},
+	"boat":                   {"OO.", "O.O", ".O."},
+	"ship":                   {"OO.", "O.O", ".OO"},
+	"tub":                    {".O.", "O.O", ".O."},
+	"pond":                   {".OO.", "O..O", "O..O", ".OO."},
+	"long boat":              {"OO..", "O.O.", ".O.O", "..O."},
+	"barge":                  {".O..", "O.O.", ".O.O", "..O."},
+	"mango":                  {".OO..", "O..O.", ".O..O", "..OO."},
+	"eater 1":                {"OO..", "O.O.", "..O.", "..OO"},
+	"snake":                  {"OO.O", "O.OO"},
+	"blinker":                {"OOO"},
+	"toad":                   {".OOO", "OOO."},
+	"beacon":                 {"OO..", "OO..", "..OO", "..OO"},
+	"clock":                  {"..O.", "O.O.", ".O.O", ".O.."},
+	"pentadecathlon":         {"..O....O..", "OO.OOOO.OO", "..O....O.."},
+	"glider":                 {".O.", "..O", "OOO"},
+	"lightweight spaceship":  {".O..O", "O....", "O...O", "OOOO."},
+	"middleweight spaceship": {"...O..", ".O...O", "O.....", "O....O", "OOOOO."},
+	"heavyweight spaceship":  {"...OO..", ".O....O", "O......", "O.....O", "OOOOOO."},
+}
+
+// objectNames maps canonical codes of Conway's Life objects to their names.
+var objectNames = map[string]string{}
+
+func init() {
+	for name, rows := range knownObjects {
+		var cells [][2]int
+		for y, row := range rows {
+			for x, c := range row {
+				if c == 'O' {
+					cells = append(cells, [2]int{x, y})
+				}
+			}
+		}
+		object := classifyObject(cells, ConwayRule, DefaultMaxPeriod)
+		objectNames[object.Code] = name
+	}
+}
+
+// CensusEntry counts objects of the same kind
+type CensusEntry struct {
+	Code  string `json:"code"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Census lists objects a universe consists of, most frequent first
+type Census []CensusEntry
+
+// String returns a census in "3x block, 2x blinker" form
+func (c Census) String() string {
+	if len(c) == 0 {
+		return "nothing"
+	}
+	parts := make([]string, len(c))
+	for i, entry := range c {
+		label := entry.Name
+		if label == "" {
+			label = entry.Code
+		}
+		parts[i] = fmt.Sprintf("%dx %s", entry.Count, label)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// object describes a classified connected component
+type object struct {
+	Code string
+	Name string
+}
+
+// takeCensus splits alive cells into 8-connected components and classifies each
+func takeCensus(cells [][2]int, width, height int, topology Topology, rule Rule, maxPeriod int) Census {
+	counts := map[object]int{}
+	// Objects of the same shape & orientation are classified once.
+	classified := map[uint64]object{}
+	objects, _ := components(cells, width, height, topology)
+	for _, component := range objects {
+		shape, _, _ := shapeOf(component)
+		o, ok := classified[shape]
+		if !ok {
+			o = classifyObject(component, rule, maxPeriod)
+			classified[shape] = o
+		}
+		counts[o]++
+	}
+
+	census := make(Census, 0, len(counts))
+	for o, count := range counts {
+		census = append(census, CensusEntry{Code: o.Code, Name: o.Name, Count: count})
+	}
+	sort.Slice(census, func(i, j int) bool {
+		if census[i].Count != census[j].Count {
+			return census[i].Count > census[j].Count
+		}
+		return census[i].Code < census[j].Code
+	})
+	return census
+}
+
+// components splits alive cells into 8-connected components
+// Neighbours beyond edges are resolved through the topology, so objects
+// crossing edges of a torus stay whole. Cells of a component get unwrapped
+// coordinates, origins holds their coordinates within the universe.
+func components(cells [][2]int, width, height int, topology Topology) (unwrapped, origins [][][2]int) {
+	alive := make(map[[2]int]bool, len(cells))
+	for _, c := range cells {
+		alive[c] = true
+	}
+	_, unbounded := topology.(Unbounded)
+
+	visited := make(map[[2]int]bool, len(cells))
+	for _, start := range cells {
+		if visited[start] {
+			continue
+		}
+		// Flood fill, keeping unwrapped coordinates of every cell.
+		component, origin := [][2]int{start}, [][2]int{start}
+		visited[start] = true
+		queue := [][2][2]int{{start, start}}
+		for len(queue) > 0 {
+			cell, unwrapped := queue[0][0], queue[0][1]
+			queue = queue[1:]
+			for _, offset := range mooreOffsets {
+				next := [2]int{cell[0] + offset[0], cell[1] + offset[1]}
+				if !unbounded {
+					x, y, ok := topology.Resolve(next[0], next[1], width, height)
+					if !ok {
+						continue
+					}
+					next = [2]int{x, y}
+				}
+				if !alive[next] || visited[next] {
+					continue
+				}
+				visited[next] = true
+				nextUnwrapped := [2]int{unwrapped[0] + offset[0], unwrapped[1] + offset[1]}
+				component = append(component, nextUnwrapped)
+				origin = append(origin, next)
+				queue = append(queue, [2][2]int{next, nextUnwrapped})
+			}
+		}
+		unwrapped = append(unwrapped, component)
+		origins = append(origins, origin)
+	}
+	return unwrapped, origins
+}
+
+// classifyObject evolves an object alone on an infinite plane and builds an
+// apgcode-like code: "xs<population>_" for still lifes, "xp<period>_" for
+// oscillators, "xq<period>_" for spaceships and "xx_" for anything else,
+// followed by the smallest extended Wechsler encoding among all phases,
+// rotations and reflections.
+func classifyObject(cells [][2]int, rule Rule, maxPeriod int) object {
+	phases := [][][2]int{cells}
+	initial, x, y := shapeOf(cells)
+	prefix := "xx_"
+	current := cells
+	for generation := 1; generation <= maxPeriod && prefix == "xx_"; generation++ {
+		current = evolveSparse(current, rule)
+		shape, nx, ny := shapeOf(current)
+		switch {
+		case shape != initial:
+			phases = append(phases, current)
+		case generation == 1 && nx == x && ny == y:
+			prefix = fmt.Sprintf("xs%d_", len(cells))
+		case nx == x && ny == y:
+			prefix = fmt.Sprintf("xp%d_", generation)
+		default:
+			prefix = fmt.Sprintf("xq%d_", generation)
+		}
+	}
+	if prefix == "xx_" {
+		// Not periodic, only the initial phase describes it.
+		phases = phases[:1]
+	}
+
+	var best string
+	for _, phase := range phases {
+		for _, code := range orientations(phase) {
+			if best == "" || len(code) < len(best) || len(code) == len(best) && code < best {
+				best = code
+			}
+		}
+	}
+	o := object{Code: prefix + best}
+	// Names are of Conway's Life objects, the same cells are other objects under other rules.
+	if rule.String() == ConwayRule.String() {
+		o.Name = objectNames[o.Code]
+	}
+	return o
+}
+
+// orientations returns extended Wechsler encodings of all 8 rotations & reflections
+func orientations(cells [][2]int) []string {
+	codes := make([]string, 0, 8)
+	transformed := make([][2]int, len(cells))
+	for t := 0; t < 8; t++ {
+		for i, c := range cells {
+			x, y := c[0], c[1]
+			if t&1 != 0 {
+				x = -x
+			}
+			if t&2 != 0 {
+				y = -y
+			}
+			if t&4 != 0 {
+				x, y = y, x
+			}
+			transformed[i] = [2]int{x, y}
+		}
+		codes = append(codes, wechsler(transformed))
+	}
+	return codes
+}
+
+// wechsler encodes cells in the extended Wechsler format
+// Cells are cut into strips 5 rows tall, every column of a strip is a digit,
+// runs of empty columns are compressed with w, x & y and strips are separated by z.
+func wechsler(cells [][2]int) string {
+	if len(cells) == 0 {
+		return "0"
+	}
+	minX, minY, maxX, maxY := cells[0][0], cells[0][1], cells[0][0], cells[0][1]
+	for _, c := range cells {
+		if c[0] < minX {
+			minX = c[0]
+		}
+		if c[0] > maxX {
+			maxX = c[0]
+		}
+		if c[1] < minY {
+			minY = c[1]
+		}
+		if c[1] > maxY {
+			maxY = c[1]
+		}
+	}
+	width := maxX - minX + 1
+	strips := (maxY-minY)/5 + 1
+	columns := make([][]int, strips)
+	for i := range columns {
+		columns[i] = make([]int, width)
+	}
+	for _, c := range cells {
+		dy := c[1] - minY
+		columns[dy/5][c[0]-minX] |= 1 << (dy % 5)
+	}
+
+	var sb strings.Builder
+	for i, strip := range columns {
+		if i > 0 {
+			sb.WriteByte('z')
+		}
+		// Trailing empty columns are omitted.
+		end := len(strip)
+		for end > 0 && strip[end-1] == 0 {
+			end--
+		}
+		zeros := 0
+		flush := func() {
+			for zeros > 0 {
+				switch {
+				case zeros == 1:
+					sb.WriteByte('0')
+					zeros = 0
+				case zeros == 2:
+					sb.WriteByte('w')
+					zeros = 0
+				case zeros == 3:
+					sb.WriteByte('x')
+					zeros = 0
+				default:
+					n := zeros - 4
+					if n > len(wechslerDigits)-1 {
+						n = len(wechslerDigits) - 1
+					}
+					sb.WriteByte('y')
+					sb.WriteByte(wechslerDigits[n])
+					zeros -= n + 4
+				}
+			}
+		}
+		for _, column := range strip[:end] {
+			if column == 0 {
+				zeros++
+				continue
+			}
+			flush()
+			sb.WriteByte(wechslerDigits[column])
+		}
+	}
+	return sb.String()
+}
+
+// evolveSparse computes the next generation of cells on an infinite plane
+func evolveSparse(cells [][2]int, rule Rule) [][2]int {
+	t := rule.transitions()
+	alive := make(map[[2]int]bool, len(cells))
+	configs := make(map[[2]int]uint8, len(cells)*4)
+	for _, c := range cells {
+		alive[c] = true
+		for i, offset := range ringOffsets {
+			// The neighbour sees the cell from the opposite direction.
+			configs[[2]int{c[0] + offset[0], c[1] + offset[1]}] |= 1 << ((i + maxNeighbours/2) % maxNeighbours)
+		}
+	}
+	next := make([][2]int, 0, len(cells))
+	for c, config := range configs {
+		if t.next(alive[c], config) {
+			next = append(next, c)
+		}
+	}
+	// Alive cells without neighbours don't show up in configurations.
+	if t.next(true, 0) {
+		for c := range alive {
+			if _, ok := configs[c]; !ok {
+				next = append(next, c)
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,157 @@
+package universe
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCensusCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells [][]bool
+		code  string
+	}{
+		{"block", parseCells("oo", "oo"), "xs4_33"},
+		{"beehive", parseCells(".oo.", "o..o", ".oo."), "xs6_696"},
+		{"boat", parseCells("oo.", "o.o", ".o."), "xs5_253"},
+		{"blinker", parseCells("ooo"), "xp2_7"},
+		{"toad", parseCells(".ooo", "ooo."), "xp2_7e"},
+		{"glider", parseCells(".o.", "..o", "ooo"), "xq4_153"},
+		{"lightweight spaceship", parseCells(".oo..", "oo.oo", ".oooo", "..oo."), "xq4_6frc"},
+	}
+	for _, tt := range tests {
+		// Every orientation of an object has the same code.
+		for _, cells := range []([][]bool){tt.cells, rotated(tt.cells), mirrored(tt.cells)} {
+			u, err := New(windowed(cells, 12, 12), Options{Rule: ConwayRule, Topology: Bounded{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			census := u.Census()
+			if len(census) != 1 || census[0].Code != tt.code || census[0].Name != tt.name || census[0].Count != 1 {
+				t.Errorf("census of a %s: %v, want 1x %s (%s)", tt.name, census, tt.name, tt.code)
+			}
+		}
+	}
+}
+
+func TestKnownObjectsNamed(t *testing.T) {
+	// Censuses split cells into 8-connected components, every known object is
+	// one of them in some phase.
+	for name, rows := range knownObjects {
+		var cells [][2]int
+		for y, row := range rows {
+			for x, c := range row {
+				if c == 'O' {
+					cells = append(cells, [2]int{x, y})
+				}
+			}
+		}
+		named := false
+		for generation := 0; generation < 4 && !named; generation++ {
+			census := takeCensus(cells, 0, 0, Unbounded{}, ConwayRule, DefaultMaxPeriod)
+			named = len(census) == 1 && census[0].Name == name
+			cells = evolveSparse(cells, ConwayRule)
+		}
+		if !named {
+			t.Errorf("census never names the %s", name)
+		}
+	}
+}
+
+func TestCensusNamesLifeObjectsOnly(t *testing.T) {
+	// A block is a still life of HighLife too, yet names are of Life objects.
+	u, err := New(windowed(parseCells("oo", "oo"), 6, 6), Options{Rule: MustParseRule("B36/S23"), Topology: Bounded{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if census := u.Census(); len(census) != 1 || census[0].Code != "xs4_33" || census[0].Name != "" {
+		t.Errorf("HighLife census %v, want an unnamed xs4_33", census)
+	}
+}
+
+// rotated turns cells by 90 degrees clockwise
+func rotated(cells [][]bool) [][]bool {
+	turned := make([][]bool, len(cells[0]))
+	for x := range turned {
+		turned[x] = make([]bool, len(cells))
+		for y := range cells {
+			turned[x][len(cells)-1-y] = cells[y][x]
+		}
+	}
+	return turned
+}
+
+// mirrored flips cells horizontally
+func mirrored(cells [][]bool) [][]bool {
+	flipped := make([][]bool, len(cells))
+	for y, row := range cells {
+		flipped[y] = make([]bool, len(row))
+		for x, alive := range row {
+			flipped[y][len(row)-1-x] = alive
+		}
+	}
+	return flipped
+}
+
+func TestCensusCounts(t *testing.T) {
+	cells := parseCells(
+		"oo....ooo.",
+		"oo........",
+		"..........",
+		".....oo...",
+		"oo...oo...",
+		"oo........",
+	)
+	u, err := New(cells, Options{Rule: ConwayRule, Topology: Bounded{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	census := u.Census()
+	if len(census) != 2 || census[0].Code != "xs4_33" || census[0].Count != 3 || census[1].Code != "xp2_7" {
+		t.Errorf("census %v, want 3x block, 1x blinker", census)
+	}
+	if census.String() != "3x block, 1x blinker" {
+		t.Errorf("census %q", census.String())
+	}
+}
+
+func TestCensusAcrossEdges(t *testing.T) {
+	// A block split by the wrapped corner of a torus is still a block.
+	cells := parseCells("o...o", ".....", ".....", "o...o")
+	u, err := New(cells, Options{Rule: ConwayRule, Topology: Torus{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if census := u.Census(); len(census) != 1 || census[0].Code != "xs4_33" {
+		t.Errorf("census %v, want 1x block", census)
+	}
+	u, err = New(cells, Options{Rule: ConwayRule, Topology: Bounded{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if census := u.Census(); len(census) != 1 || census[0].Count != 4 {
+		t.Errorf("census %v, want 4 single cells", census)
+	}
+}
+
+func TestPrepareCensusWhileEvolving(t *testing.T) {
+	u, err := New(randomCells(64, 64, 0.4, 5), Options{Rule: ConwayRule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := u.Census()
+	census := u.PrepareCensus()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			u.Evolve()
+		}
+	}()
+	got := census()
+	wg.Wait()
+	if got.String() != want.String() {
+		t.Errorf("census %s, want %s", got, want)
+	}
+}
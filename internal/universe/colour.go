@@ -0,0 +1,149 @@
+package universe
+
+import (
+	"fmt"
+)
+
+// maxColours is the largest supported palette.
+const maxColours = 256
+
+// speciesPalette holds default colours of species of multi-colour rules.
+var speciesPalette = []string{"#fa725a", "#0099ff", "#a1ff6c", "#FDCB58"}
+
+// colourPlane tracks colours of alive cells of a multi-colour universe
+// Surviving cells keep their colour, newborn ones take the most common colour
+// among their alive neighbours, the lowest palette index wins ties.
+type colourPlane struct {
+	palette []string
+	// fillMissing gives a newborn whose parents all differ the only colour
+	// none of them has, as QuadLife does.
+	fillMissing bool
+	// colours holds palette indices, rows first, meaningful for alive cells only.
+	colours [][]uint8
+	// alive holds cells of the generation colours describe.
+	alive [][]bool
+	// next & counts are reused by every generation, so nothing is allocated while evolving.
+	next   [][]bool
+	counts []int
+}
+
+// shape mixes colours of alive cells into the hash of their shape, cells are
+// sorted by shapeOf & may be unwrapped across the seam of a torus
+func (c *colourPlane) shape(hash uint64, cells [][2]int) uint64 {
+	for _, cell := range cells {
+		y := cell[1] % len(c.colours)
+		hash = mixHash(hash, uint64(c.colours[y][cell[0]%len(c.colours[y])]))
+	}
+	// 0 is reserved for an unknown shape.
+	if hash == 0 {
+		hash = 1
+	}
+	return hash
+}
+
+// newColourPlane validates palette indices of cells, rows first
+func newColourPlane(palette []string, colours [][]uint8, alive [][]bool, fillMissing bool) (*colourPlane, error) {
+	if len(palette) > maxColours {
+		return nil, fmt.Errorf("palette has %d colours, %d at most", len(palette), maxColours)
+	}
+	if len(colours) != len(alive) {
+		return nil, fmt.Errorf("colours have %d rows, expected %d", len(colours), len(alive))
+	}
+	plane := &colourPlane{
+		palette:     palette,
+		fillMissing: fillMissing,
+		colours:     make([][]uint8, len(colours)),
+		alive:       alive,
+	}
+	for y, row := range colours {
+		if len(row) != len(alive[y]) {
+			return nil, fmt.Errorf("colours row %d has %d cells, expected %d", y, len(row), len(alive[y]))
+		}
+		for x, c := range row {
+			if int(c) >= len(palette) {
+				return nil, fmt.Errorf("colour of cell (%d, %d) is %d, palette has %d colours", x, y, c, len(palette))
+			}
+		}
+		plane.colours[y] = append([]uint8(nil), row...)
+	}
+	return plane, nil
+}
+
+// evolve recolours cells of the next generation of the engine
+// Alive cells are read into a buffer swapped with the previous generation's.
+func (p *colourPlane) evolve(e engine, topology Topology) {
+	height, width := len(p.alive), len(p.alive[0])
+	if p.next == nil {
+		p.next = make([][]bool, height)
+		for y := range p.next {
+			p.next[y] = make([]bool, width)
+		}
+		p.counts = make([]int, len(p.palette))
+	}
+	next, counts := p.next, p.counts
+	for y, row := range next {
+		for x := range row {
+			row[x] = e.cell(x, y)
+		}
+	}
+	for y, row := range next {
+		for x, alive := range row {
+			if !alive || p.alive[y][x] {
+				continue
+			}
+			for i := range counts {
+				counts[i] = 0
+			}
+			parents := 0
+			for _, offset := range mooreOffsets {
+				nx, ny, ok := topology.Resolve(x+offset[0], y+offset[1], width, height)
+				if ok && p.alive[ny][nx] {
+					counts[p.colours[ny][nx]]++
+					parents++
+				}
+			}
+			var best, missing uint8
+			for i, count := range counts {
+				if count > counts[best] {
+					best = uint8(i)
+				}
+				if count == 0 {
+					missing = uint8(i)
+				}
+			}
+			if p.fillMissing && counts[best] == 1 && parents == len(counts)-1 {
+				best = missing
+			}
+			p.colours[y][x] = best
+		}
+	}
+	p.alive, p.next = next, p.alive
+}
+
+// matrix returns a copy of palette indices, dead cells are -1
+func (p *colourPlane) matrix() [][]int {
+	matrix := make([][]int, len(p.colours))
+	for y, row := range p.colours {
+		matrix[y] = make([]int, len(row))
+		for x, c := range row {
+			matrix[y][x] = -1
+			if p.alive[y][x] {
+				matrix[y][x] = int(c)
+			}
+		}
+	}
+	return matrix
+}
+
+// hash returns a hash of colours of alive cells
+func (p *colourPlane) hash() uint64 {
+	hash := uint64(len(p.palette))
+	for y, row := range p.colours {
+		for x, c := range row {
+			if p.alive[y][x] {
+				hash = mixHash(hash, uint64(c))
+			}
+		}
+	}
+	return hash
+}
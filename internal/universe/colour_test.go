@@ -0,0 +1,80 @@
+package universe
+
+import "testing"
+
+func TestColourBirths(t *testing.T) {
+	// A blinker turns upright, cells above & below the middle one are born of
+	// the 3 cells of the row, the middle one survives.
+	blinker := parseCells(".....", ".....", ".ooo.", ".....", ".....")
+	tests := []struct {
+		name    string
+		rule    string
+		palette []string
+		row     []uint8
+		want    uint8
+	}{
+		{"immigration majority", "immigration", nil, []uint8{1, 0, 1}, 1},
+		{"immigration majority of the first", "immigration", nil, []uint8{0, 1, 0}, 0},
+		{"quadlife majority", "quadlife", nil, []uint8{3, 1, 3}, 3},
+		// Parents of 3 different species give birth to the fourth one.
+		{"quadlife missing species", "quadlife", nil, []uint8{0, 3, 1}, 2},
+		{"quadlife missing first species", "quadlife", nil, []uint8{2, 1, 3}, 0},
+		// Without the QuadLife rule the lowest palette index wins ties.
+		{"tie", "B3/S23", []string{"#f00", "#0f0", "#00f", "#fff"}, []uint8{3, 2, 1}, 1},
+	}
+	for _, tt := range tests {
+		colours := make([][]uint8, 5)
+		for y := range colours {
+			colours[y] = make([]uint8, 5)
+		}
+		copy(colours[2][1:], tt.row)
+		u, err := New(blinker, Options{
+			Rule:     MustParseRule(tt.rule),
+			Topology: Bounded{},
+			Palette:  tt.palette,
+			Colours:  colours,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		u.Evolve()
+		if !equalCells(u.Cells(), parseCells(".....", "..o..", "..o..", "..o..", ".....")) {
+			t.Fatalf("%s: blinker evolved into %v", tt.name, u.Cells())
+		}
+		for _, y := range []int{1, 3} {
+			if got := u.CellColour(2, y); got != tt.want {
+				t.Errorf("%s: cell (2, %d) born with colour %d, want %d", tt.name, y, got, tt.want)
+			}
+		}
+		if got := u.CellColour(2, 2); got != tt.row[1] {
+			t.Errorf("%s: surviving cell changed colour from %d to %d", tt.name, tt.row[1], got)
+		}
+	}
+}
+
+func TestColoursEvolveWithCells(t *testing.T) {
+	// Colours follow a glider across the seam of a torus for whole periods.
+	cells := windowed(parseCells(".o.", "..o", "ooo"), 9, 9)
+	colours := make([][]uint8, 9)
+	for y := range colours {
+		colours[y] = make([]uint8, 9)
+		for x := range colours[y] {
+			colours[y][x] = uint8(x+y) % 2
+		}
+	}
+	u, err := New(cells, Options{Rule: MustParseRule("immigration"), Topology: Torus{}, Colours: colours})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for generation := 1; generation <= 36; generation++ {
+		u.Evolve()
+		matrix := u.colours.matrix()
+		for y, row := range u.Cells() {
+			for x, alive := range row {
+				if alive != (matrix[y][x] >= 0) {
+					t.Fatalf("generation %d: cell (%d, %d) alive %t, colour %d", generation, x, y, alive, matrix[y][x])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package universe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultEngine is used when no engine is given.
+const DefaultEngine = "bitwise"
+
+// engine stores cells of a Universe and computes next generations
+type engine interface {
+	// step advances cells by 2^exponent generations.
+	step(exponent uint)
+	// maxStep returns the largest exponent supported by step.
+	maxStep() uint
+	// cell tells if the cell at (x, y) is alive.
+	cell(x, y int) bool
+	// cells returns a matrix of cells, rows first.
+	cells() [][]bool
+	// population returns the number of alive cells.
+	population() int
+	// hash returns a position dependent hash of cells.
+	hash() uint64
+	// aliveCells returns coordinates of alive cells, in any order.
+	aliveCells() [][2]int
+}
+
+// packedEngine is an engine keeping cells in words of 64 cells
+type packedEngine interface {
+	engine
+	// packed returns words of cells, rows first, each row starting with a new
+	// word & bit x%64 of word x/64 of a row storing cell x.
+	packed() []uint64
+}
+
+// engineFactory creates an engine from an initial matrix of cells
+// It fails when the engine can't handle the rule, the topology or the grid.
+type engineFactory func(cells [][]bool, rule Rule, topology Topology, grid Grid) (engine, error)
+
+// engineSpec describes a known engine
+type engineSpec struct {
+	factory engineFactory
+	// topology is used when a universe doesn't specify one.
+	topology Topology
+}
+
+// engines holds all known engines by name.
+var engines = map[string]engineSpec{
+	"bitwise":    {factory: newBitwiseEngine, topology: DefaultTopology},
+	"scalar":     {factory: newScalarEngine, topology: DefaultTopology},
+	"hashlife":   {factory: newHashlifeEngine, topology: Unbounded{}},
+	"multistate": {factory: newMultistateEngine, topology: DefaultTopology},
+}
+
+// defaultEngineFor returns the engine used for a rule on a grid when none is given
+func defaultEngineFor(rule Rule, grid Grid) string {
+	switch {
+	case !rule.isTwoStateMoore() || !isSquare(grid):
+		return "multistate"
+	case rule.Isotropic != nil:
+		return "scalar"
+	default:
+		return DefaultEngine
+	}
+}
+
+// checkTwoStateMoore fails for rules whose cells have more states than alive &
+// dead or which look at other neighbours than the 8 Moore ones
+func checkTwoStateMoore(engine string, rule Rule) error {
+	if rule.IsGenerations() {
+		return fmt.Errorf("%s engine doesn't support Generations rules, use multistate engine", engine)
+	}
+	if rule.Extended != nil {
+		return fmt.Errorf("%s engine doesn't support Larger than Life rules, use multistate engine", engine)
+	}
+	return nil
+}
+
+// checkLifeLike fails for rules checkTwoStateMoore fails for and for isotropic
+// non-totalistic rules, which need more than a count of neighbours
+func checkLifeLike(engine string, rule Rule) error {
+	if err := checkTwoStateMoore(engine, rule); err != nil {
+		return err
+	}
+	if rule.Isotropic != nil {
+		return fmt.Errorf("%s engine doesn't support isotropic non-totalistic rules, use scalar engine", engine)
+	}
+	return nil
+}
+
+// Engines returns names of all known engines
+func Engines() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getEngineSpec finds an engine by its name
+func getEngineSpec(name string) (engineSpec, error) {
+	spec, ok := engines[name]
+	if !ok {
+		return spec, fmt.Errorf("unknown engine %q, expected one of: %s", name, strings.Join(Engines(), ", "))
+	}
+	return spec, nil
+}
+
+// mixHash combines several hashes into one
+func mixHash(values ...uint64) uint64 {
+	hash := uint64(14695981039346656037)
+	for _, v := range values {
+		hash ^= v
+		hash *= 1099511628211
+		hash ^= hash >> 29
+	}
+	return hash
+}
@@ -0,0 +1,271 @@
+package universe
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const wordBits = 64
+
+// bitwiseEngine packs 64 cells into every uint64 word and computes a next
+// generation for a whole word at once with bit-sliced adders.
+// Two buffers are swapped every generation, so no allocation happens while evolving.
+type bitwiseEngine struct {
+	rule     Rule
+	topology Topology
+	width    int
+	height   int
+	// words is the number of words per row, bit x%64 of word x/64 stores cell x.
+	words int
+	cur   []uint64
+	next  []uint64
+	// padded is a copy of cur surrounded by a halo of cells resolved
+	// through the topology, so edges need no special treatment.
+	padded      []uint64
+	paddedWords int
+	// lastMask clears bits beyond the width in the last word of a row.
+	lastMask uint64
+	// birth & survival list neighbour counts enabled by the rule.
+	birth    []int
+	survival []int
+}
+
+// newBitwiseEngine creates a bitwiseEngine from a matrix of cells
+func newBitwiseEngine(cells [][]bool, rule Rule, topology Topology, grid Grid) (engine, error) {
+	if err := checkSquare("bitwise", grid); err != nil {
+		return nil, err
+	}
+	if err := checkLifeLike("bitwise", rule); err != nil {
+		return nil, err
+	}
+	if _, ok := topology.(Unbounded); ok {
+		return nil, fmt.Errorf("bitwise engine can't evolve %q topology", topology.Name())
+	}
+	height, width := len(cells), len(cells[0])
+	e := &bitwiseEngine{
+		rule:        rule,
+		topology:    topology,
+		width:       width,
+		height:      height,
+		words:       (width + wordBits - 1) / wordBits,
+		paddedWords: (width + 2 + wordBits - 1) / wordBits,
+		lastMask:    ^uint64(0),
+	}
+	if rem := width % wordBits; rem != 0 {
+		e.lastMask = uint64(1)<<rem - 1
+	}
+	e.cur = make([]uint64, e.words*height)
+	e.next = make([]uint64, e.words*height)
+	e.padded = make([]uint64, e.paddedWords*(height+2))
+	for n := range rule.Birth {
+		if rule.Birth[n] {
+			e.birth = append(e.birth, n)
+		}
+		if rule.Survival[n] {
+			e.survival = append(e.survival, n)
+		}
+	}
+	for y := range cells {
+		for x, alive := range cells[y] {
+			if alive {
+				e.cur[y*e.words+x/wordBits] |= 1 << (x % wordBits)
+			}
+		}
+	}
+	return e, nil
+}
+
+// row returns words of the row y
+func (e *bitwiseEngine) row(y int) []uint64 {
+	return e.cur[y*e.words : (y+1)*e.words]
+}
+
+// step advances cells generation by generation
+func (e *bitwiseEngine) step(exponent uint) {
+	for i := 0; i < 1<<exponent; i++ {
+		e.nextGeneration()
+	}
+}
+
+// maxStep returns the largest supported step exponent, evolving is linear so no jumps are allowed
+func (e *bitwiseEngine) maxStep() uint {
+	return 0
+}
+
+// nextGeneration computes the next generation into the spare buffer and swaps buffers
+func (e *bitwiseEngine) nextGeneration() {
+	e.fillPadded()
+
+	for y := 0; y < e.height; y++ {
+		above := e.padded[y*e.paddedWords : (y+1)*e.paddedWords]
+		middle := e.padded[(y+1)*e.paddedWords : (y+2)*e.paddedWords]
+		below := e.padded[(y+2)*e.paddedWords : (y+3)*e.paddedWords]
+		out := e.next[y*e.words : (y+1)*e.words]
+
+		for i := range out {
+			// Padded bit x+1 holds cell x, so for the output cell x its west,
+			// centre and east neighbours are padded bits x, x+1 and x+2.
+			n1, n2, n3 := above[i], shiftRight(above, i, 1), shiftRight(above, i, 2)
+			n4, centre, n5 := middle[i], shiftRight(middle, i, 1), shiftRight(middle, i, 2)
+			n6, n7, n8 := below[i], shiftRight(below, i, 1), shiftRight(below, i, 2)
+
+			// Bit-sliced sum of 8 neighbours into 4 bits: count = c0 + 2*c1 + 4*c2 + 8*c3.
+			sA, cA := fullAdder(n1, n2, n3)
+			sB, cB := fullAdder(n4, n5, n6)
+			sC, cC := n7^n8, n7&n8
+			c0, cD := fullAdder(sA, sB, sC)
+			t, u := fullAdder(cA, cB, cC)
+			c1, v := t^cD, t&cD
+			c2, c3 := u^v, u&v
+
+			var word uint64
+			for _, n := range e.birth {
+				word |= countEquals(n, c0, c1, c2, c3) &^ centre
+			}
+			for _, n := range e.survival {
+				word |= countEquals(n, c0, c1, c2, c3) & centre
+			}
+			out[i] = word
+		}
+		out[len(out)-1] &= e.lastMask
+	}
+	e.cur, e.next = e.next, e.cur
+}
+
+// fillPadded copies cells into the padded buffer and resolves the halo
+func (e *bitwiseEngine) fillPadded() {
+	for y := -1; y <= e.height; y++ {
+		dst := e.padded[(y+1)*e.paddedWords : (y+2)*e.paddedWords]
+		if y < 0 || y == e.height {
+			// Halo rows, every cell is resolved through the topology.
+			for i := range dst {
+				dst[i] = 0
+			}
+			for x := -1; x <= e.width; x++ {
+				if e.resolve(x, y) {
+					dst[(x+1)/wordBits] |= 1 << ((x + 1) % wordBits)
+				}
+			}
+			continue
+		}
+		// Shift the row by one bit to make room for the west halo cell.
+		src := e.row(y)
+		var carry uint64
+		for i := range dst {
+			var word uint64
+			if i < len(src) {
+				word = src[i]
+			}
+			dst[i] = word<<1 | carry
+			carry = word >> (wordBits - 1)
+		}
+		if e.resolve(-1, y) {
+			dst[0] |= 1
+		}
+		if e.resolve(e.width, y) {
+			dst[(e.width+1)/wordBits] |= 1 << ((e.width + 1) % wordBits)
+		}
+	}
+}
+
+// resolve tells if a cell, possibly outside the universe, is alive
+func (e *bitwiseEngine) resolve(x, y int) bool {
+	rx, ry, ok := e.topology.Resolve(x, y, e.width, e.height)
+	return ok && e.cell(rx, ry)
+}
+
+// cell tells if the cell at (x, y) is alive
+func (e *bitwiseEngine) cell(x, y int) bool {
+	return e.cur[y*e.words+x/wordBits]&(1<<(x%wordBits)) != 0
+}
+
+// packed returns words of cells, not a copy
+func (e *bitwiseEngine) packed() []uint64 {
+	return e.cur
+}
+
+// cells returns a matrix of cells
+func (e *bitwiseEngine) cells() [][]bool {
+	matrix := make([][]bool, e.height)
+	for y := range matrix {
+		matrix[y] = make([]bool, e.width)
+		for x := range matrix[y] {
+			matrix[y][x] = e.cell(x, y)
+		}
+	}
+	return matrix
+}
+
+// aliveCells returns coordinates of alive cells, rows first
+func (e *bitwiseEngine) aliveCells() [][2]int {
+	cells := make([][2]int, 0, e.population())
+	for y := 0; y < e.height; y++ {
+		for i, word := range e.row(y) {
+			for word != 0 {
+				bit := bits.TrailingZeros64(word)
+				cells = append(cells, [2]int{i*wordBits + bit, y})
+				word &= word - 1
+			}
+		}
+	}
+	return cells
+}
+
+// population returns the number of alive cells
+func (e *bitwiseEngine) population() int {
+	var count int
+	for _, word := range e.cur {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// hash calculates an FNV-1a hash of all words
+func (e *bitwiseEngine) hash() uint64 {
+	return hashWords(e.cur)
+}
+
+// hashWords calculates an FNV-1a hash of words, byte by byte
+func hashWords(words []uint64) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	for _, word := range words {
+		for i := 0; i < 8; i++ {
+			hash ^= word & 0xff
+			hash *= prime64
+			word >>= 8
+		}
+	}
+	return hash
+}
+
+// shiftRight returns the word i of a multi-word row shifted right by s bits
+func shiftRight(row []uint64, i int, s uint) uint64 {
+	word := row[i] >> s
+	if i+1 < len(row) {
+		word |= row[i+1] << (wordBits - s)
+	}
+	return word
+}
+
+// fullAdder adds 3 bit vectors, returns sum & carry vectors
+func fullAdder(a, b, c uint64) (uint64, uint64) {
+	t := a ^ b
+	return t ^ c, a&b | t&c
+}
+
+// countEquals returns a mask of bits where the 4-bit sliced count equals n
+func countEquals(n int, c0, c1, c2, c3 uint64) uint64 {
+	mask := ^uint64(0)
+	for i, c := range [4]uint64{c0, c1, c2, c3} {
+		if n>>i&1 == 1 {
+			mask &= c
+		} else {
+			mask &^= c
+		}
+	}
+	return mask
+}
@@ -0,0 +1,361 @@
+package universe
+
+import (
+	"fmt"
+)
+
+// maxHashlifeStep limits a jump to 2^maxHashlifeStep generations per step,
+// generation numbers last for millions of jumps.
+const maxHashlifeStep = 40
+
+var (
+	// maxHashlifeNodes is the number of canonical nodes after which they're dropped.
+	maxHashlifeNodes = 1 << 21
+	// maxHashlifeMemo is the number of memoized results after which they're dropped.
+	maxHashlifeMemo = 1 << 22
+)
+
+// node is a canonical quadtree node covering 2^level x 2^level cells
+// Nodes are hash-consed, equal subtrees share the same pointer.
+type node struct {
+	nw, ne, sw, se *node
+	level          uint
+	population     int
+	hash           uint64
+}
+
+// memoKey identifies a memoized successor of a node advanced by 2^step generations
+type memoKey struct {
+	node *node
+	step uint
+}
+
+// hashlifeEngine evolves an unbounded plane with Gosper's HashLife algorithm
+// Universe dimensions only describe a window, starting at (0, 0), onto the plane.
+type hashlifeEngine struct {
+	rule   Rule
+	width  int
+	height int
+	root   *node
+	// originX & originY are plane coordinates of the root's top left cell.
+	originX int
+	originY int
+	alive   *node
+	dead    *node
+	nodes   map[[4]*node]*node
+	empties []*node
+	memo    map[memoKey]*node
+}
+
+// newHashlifeEngine creates a hashlifeEngine from a window of cells
+func newHashlifeEngine(cells [][]bool, rule Rule, topology Topology, grid Grid) (engine, error) {
+	if err := checkSquare("hashlife", grid); err != nil {
+		return nil, err
+	}
+	if err := checkLifeLike("hashlife", rule); err != nil {
+		return nil, err
+	}
+	if rule.Birth[0] {
+		return nil, fmt.Errorf("hashlife engine doesn't support B0 rules")
+	}
+	if _, ok := topology.(Unbounded); !ok {
+		return nil, fmt.Errorf("hashlife engine requires %q topology, got %q", Unbounded{}.Name(), topology.Name())
+	}
+	e := &hashlifeEngine{
+		rule:   rule,
+		width:  len(cells[0]),
+		height: len(cells),
+		dead:   &node{hash: 0x9e3779b97f4a7c15},
+		alive:  &node{population: 1, hash: 0xc2b2ae3d27d4eb4f},
+	}
+	e.resetCaches()
+
+	// Build the smallest root covering the whole window.
+	var level uint = 2
+	for 1<<level < e.width || 1<<level < e.height {
+		level++
+	}
+	e.root = e.build(cells, 0, 0, level)
+	return e, nil
+}
+
+// resetCaches drops canonical nodes & memoized results
+// Existing nodes stay valid, they just won't be shared with new ones, so caches
+// may be dropped at any time, even in the middle of a step.
+func (e *hashlifeEngine) resetCaches() {
+	e.nodes = make(map[[4]*node]*node)
+	e.memo = make(map[memoKey]*node)
+	e.empties = []*node{e.dead}
+}
+
+// build creates a node of the given level from the window cells at (x, y)
+func (e *hashlifeEngine) build(cells [][]bool, x, y int, level uint) *node {
+	if x >= e.width || y >= e.height {
+		return e.empty(level)
+	}
+	if level == 0 {
+		if cells[y][x] {
+			return e.alive
+		}
+		return e.dead
+	}
+	half := 1 << (level - 1)
+	return e.join(
+		e.build(cells, x, y, level-1),
+		e.build(cells, x+half, y, level-1),
+		e.build(cells, x, y+half, level-1),
+		e.build(cells, x+half, y+half, level-1),
+	)
+}
+
+// join returns the canonical node made of 4 quadrants
+func (e *hashlifeEngine) join(nw, ne, sw, se *node) *node {
+	key := [4]*node{nw, ne, sw, se}
+	if n, ok := e.nodes[key]; ok {
+		return n
+	}
+	if len(e.nodes) >= maxHashlifeNodes {
+		e.nodes = make(map[[4]*node]*node)
+	}
+	n := &node{
+		nw: nw, ne: ne, sw: sw, se: se,
+		level:      nw.level + 1,
+		population: nw.population + ne.population + sw.population + se.population,
+		hash:       mixHash(nw.hash, ne.hash, sw.hash, se.hash, uint64(nw.level+1)),
+	}
+	e.nodes[key] = n
+	return n
+}
+
+// empty returns an empty node of the given level
+func (e *hashlifeEngine) empty(level uint) *node {
+	for uint(len(e.empties)) <= level {
+		prev := e.empties[len(e.empties)-1]
+		e.empties = append(e.empties, e.join(prev, prev, prev, prev))
+	}
+	return e.empties[level]
+}
+
+// centre returns the centre quarter of a node
+func (e *hashlifeEngine) centre(n *node) *node {
+	return e.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// expand surrounds the root with an empty border, doubling its size
+func (e *hashlifeEngine) expand() {
+	r := e.root
+	border := e.empty(r.level - 1)
+	e.root = e.join(
+		e.join(border, border, border, r.nw),
+		e.join(border, border, r.ne, border),
+		e.join(border, r.sw, border, border),
+		e.join(r.se, border, border, border),
+	)
+	e.originX -= 1 << (r.level - 1)
+	e.originY -= 1 << (r.level - 1)
+}
+
+// isPadded tells if all alive cells of the root lie in its centre quarter
+func (e *hashlifeEngine) isPadded() bool {
+	r := e.root
+	return r.nw.population == r.nw.se.population &&
+		r.ne.population == r.ne.sw.population &&
+		r.sw.population == r.sw.ne.population &&
+		r.se.population == r.se.nw.population
+}
+
+// step advances the plane by 2^exponent generations
+func (e *hashlifeEngine) step(exponent uint) {
+	// The pattern must sit in the centre with a border wide enough for
+	// anything travelling at the speed of light during the jump.
+	for e.root.level < exponent+2 || !e.isPadded() {
+		e.expand()
+	}
+	e.expand()
+	level := e.root.level
+	e.root = e.successor(e.root, exponent)
+	e.originX += 1 << (level - 2)
+	e.originY += 1 << (level - 2)
+}
+
+// successor returns the centre half of a node advanced by 2^step generations
+// The step is capped at level-2, the most a node can see ahead.
+func (e *hashlifeEngine) successor(n *node, step uint) *node {
+	if n.population == 0 {
+		return e.empty(n.level - 1)
+	}
+	if step > n.level-2 {
+		step = n.level - 2
+	}
+	key := memoKey{node: n, step: step}
+	if result, ok := e.memo[key]; ok {
+		return result
+	}
+	if n.level == 2 {
+		result := e.base(n)
+		e.remember(key, result)
+		return result
+	}
+
+	// 9 overlapping sub-nodes, each half the size of n.
+	n00, n01, n02 := n.nw, e.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw), n.ne
+	n10, n11, n12 := e.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne), e.centre(n), e.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne)
+	n20, n21, n22 := n.sw, e.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw), n.se
+
+	// At full speed both halves of the jump are computed recursively,
+	// otherwise the first half does the whole jump and the second one only crops.
+	innerStep := step
+	if step == n.level-2 {
+		innerStep = step - 1
+	}
+	c00, c01, c02 := e.successor(n00, innerStep), e.successor(n01, innerStep), e.successor(n02, innerStep)
+	c10, c11, c12 := e.successor(n10, innerStep), e.successor(n11, innerStep), e.successor(n12, innerStep)
+	c20, c21, c22 := e.successor(n20, innerStep), e.successor(n21, innerStep), e.successor(n22, innerStep)
+
+	quadrants := [4]*node{
+		e.join(c00, c01, c10, c11),
+		e.join(c01, c02, c11, c12),
+		e.join(c10, c11, c20, c21),
+		e.join(c11, c12, c21, c22),
+	}
+	for i, q := range quadrants {
+		if step == n.level-2 {
+			quadrants[i] = e.successor(q, innerStep)
+		} else {
+			quadrants[i] = e.centre(q)
+		}
+	}
+	result := e.join(quadrants[0], quadrants[1], quadrants[2], quadrants[3])
+	e.remember(key, result)
+	return result
+}
+
+// remember memoizes a successor, memoized results are dropped when there are too many
+func (e *hashlifeEngine) remember(key memoKey, result *node) {
+	if len(e.memo) >= maxHashlifeMemo {
+		e.memo = make(map[memoKey]*node)
+	}
+	e.memo[key] = result
+}
+
+// base advances the centre 2x2 cells of a 4x4 node by one generation
+func (e *hashlifeEngine) base(n *node) *node {
+	var grid [4][4]bool
+	for qy, row := range [2][2]*node{{n.nw, n.ne}, {n.sw, n.se}} {
+		for qx, q := range row {
+			grid[qy*2][qx*2] = q.nw == e.alive
+			grid[qy*2][qx*2+1] = q.ne == e.alive
+			grid[qy*2+1][qx*2] = q.sw == e.alive
+			grid[qy*2+1][qx*2+1] = q.se == e.alive
+		}
+	}
+	var next [4]*node
+	for i, c := range [4][2]int{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+		var neighbours int
+		for _, offset := range mooreOffsets {
+			if grid[c[1]+offset[1]][c[0]+offset[0]] {
+				neighbours++
+			}
+		}
+		alive := e.rule.Birth[neighbours]
+		if grid[c[1]][c[0]] {
+			alive = e.rule.Survival[neighbours]
+		}
+		next[i] = e.dead
+		if alive {
+			next[i] = e.alive
+		}
+	}
+	return e.join(next[0], next[1], next[2], next[3])
+}
+
+// maxStep returns the largest supported jump exponent
+func (e *hashlifeEngine) maxStep() uint {
+	return maxHashlifeStep
+}
+
+// cell tells if the cell at window coordinates (x, y) is alive
+func (e *hashlifeEngine) cell(x, y int) bool {
+	x, y = x-e.originX, y-e.originY
+	n := e.root
+	if x < 0 || y < 0 || x >= 1<<n.level || y >= 1<<n.level {
+		return false
+	}
+	for n.level > 0 {
+		if n.population == 0 {
+			return false
+		}
+		half := 1 << (n.level - 1)
+		switch {
+		case x < half && y < half:
+			n = n.nw
+		case y < half:
+			n, x = n.ne, x-half
+		case x < half:
+			n, y = n.sw, y-half
+		default:
+			n, x, y = n.se, x-half, y-half
+		}
+	}
+	return n == e.alive
+}
+
+// aliveCells returns plane coordinates of all alive cells
+func (e *hashlifeEngine) aliveCells() [][2]int {
+	cells := make([][2]int, 0, e.root.population)
+	e.collect(&cells, e.root, e.originX, e.originY)
+	return cells
+}
+
+// collect appends coordinates of alive cells of a node placed at (x, y)
+func (e *hashlifeEngine) collect(cells *[][2]int, n *node, x, y int) {
+	if n.population == 0 {
+		return
+	}
+	if n.level == 0 {
+		*cells = append(*cells, [2]int{x, y})
+		return
+	}
+	half := 1 << (n.level - 1)
+	e.collect(cells, n.nw, x, y)
+	e.collect(cells, n.ne, x+half, y)
+	e.collect(cells, n.sw, x, y+half)
+	e.collect(cells, n.se, x+half, y+half)
+}
+
+// cells returns the window of cells
+func (e *hashlifeEngine) cells() [][]bool {
+	matrix := make([][]bool, e.height)
+	for y := range matrix {
+		matrix[y] = make([]bool, e.width)
+	}
+	e.fill(matrix, e.root, e.originX, e.originY)
+	return matrix
+}
+
+// fill marks alive cells of a node placed at (x, y) within the window
+func (e *hashlifeEngine) fill(matrix [][]bool, n *node, x, y int) {
+	size := 1 << n.level
+	if n.population == 0 || x >= e.width || y >= e.height || x+size <= 0 || y+size <= 0 {
+		return
+	}
+	if n.level == 0 {
+		matrix[y][x] = true
+		return
+	}
+	half := size / 2
+	e.fill(matrix, n.nw, x, y)
+	e.fill(matrix, n.ne, x+half, y)
+	e.fill(matrix, n.sw, x, y+half)
+	e.fill(matrix, n.se, x+half, y+half)
+}
+
+// population returns the number of alive cells on the whole plane
+func (e *hashlifeEngine) population() int {
+	return e.root.population
+}
+
+// hash returns a position dependent hash of the whole plane
+func (e *hashlifeEngine) hash() uint64 {
+	return mixHash(e.root.hash, uint64(e.root.level), uint64(e.originX), uint64(e.originY))
+}
@@ -0,0 +1,110 @@
+package universe
+
+import (
+	"math"
+	"testing"
+)
+
+// windowed places cells in the middle of an empty width x height window
+func windowed(cells [][]bool, width, height int) [][]bool {
+	window := make([][]bool, height)
+	for y := range window {
+		window[y] = make([]bool, width)
+	}
+	offsetX, offsetY := (width-len(cells[0]))/2, (height-len(cells))/2
+	for y, row := range cells {
+		copy(window[offsetY+y][offsetX:], row)
+	}
+	return window
+}
+
+func TestHashlifeMatchesBitwise(t *testing.T) {
+	// Within 20 generations nothing gets near edges of the window.
+	soup := randomCells(16, 16, 0.4, 7)
+	cells := windowed(soup, 64, 64)
+	for _, rule := range []string{"B3/S23", "B36/S23", "B3678/S34678"} {
+		for _, step := range []uint{0, 1, 2} {
+			hashlife, err := New(cells, Options{Rule: MustParseRule(rule), Engine: "hashlife", Step: step})
+			if err != nil {
+				t.Fatal(err)
+			}
+			bitwise, err := New(cells, Options{Rule: MustParseRule(rule), Engine: "bitwise", Topology: Bounded{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for tick := 0; tick < 20>>step; tick++ {
+				hashlife.Evolve()
+				for i := 0; i < 1<<step; i++ {
+					bitwise.Evolve()
+				}
+				if !equalCells(hashlife.Cells(), bitwise.Cells()) {
+					t.Fatalf("%s step %d: engines differ at generation %d", rule, step, hashlife.Stats().Generation)
+				}
+			}
+		}
+	}
+}
+
+func TestHashlifeSteps(t *testing.T) {
+	glider := parseCells(".o.", "..o", "ooo")
+	if _, err := New(glider, Options{Rule: ConwayRule, Engine: "hashlife", Step: maxHashlifeStep + 1}); err == nil {
+		t.Errorf("step %d accepted", maxHashlifeStep+1)
+	}
+	u, err := New(glider, Options{Rule: ConwayRule, Engine: "hashlife", Step: maxHashlifeStep})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A glider keeps its population however far it flies.
+	for i := 0; i < 8; i++ {
+		u.Evolve()
+		if stats := u.Stats(); stats.Alive != 5 || stats.Generation != (i+1)<<maxHashlifeStep {
+			t.Fatalf("tick %d: generation %d, population %d", i, stats.Generation, stats.Alive)
+		}
+	}
+}
+
+func TestGenerationNumberDoesNotOverflow(t *testing.T) {
+	u, err := New(parseCells(".o.", "..o", "ooo"), Options{Rule: ConwayRule, Engine: "hashlife", Step: maxHashlifeStep})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.generationNumber = math.MaxInt - 3<<maxHashlifeStep
+	for i := 0; i < 8; i++ {
+		u.Evolve()
+		if u.generationNumber < 0 {
+			t.Fatalf("generation number overflowed at tick %d", i)
+		}
+	}
+	if u.generationNumber != math.MaxInt {
+		t.Errorf("generation number %d, want %d", u.generationNumber, math.MaxInt)
+	}
+}
+
+func TestHashlifeBoundsCaches(t *testing.T) {
+	defer func(nodes, memo int) { maxHashlifeNodes, maxHashlifeMemo = nodes, memo }(maxHashlifeNodes, maxHashlifeMemo)
+	maxHashlifeNodes, maxHashlifeMemo = 500, 500
+
+	cells := windowed(randomCells(32, 32, 0.4, 3), 96, 96)
+	u, err := New(cells, Options{Rule: ConwayRule, Engine: "hashlife", Step: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitwise, err := New(cells, Options{Rule: ConwayRule, Engine: "bitwise", Topology: Bounded{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := u.engine.(*hashlifeEngine)
+	for i := 0; i < 5; i++ {
+		u.Evolve()
+		for j := 0; j < 4; j++ {
+			bitwise.Evolve()
+		}
+		if len(e.nodes) > maxHashlifeNodes || len(e.memo) > maxHashlifeMemo {
+			t.Fatalf("caches grew to %d nodes, %d results", len(e.nodes), len(e.memo))
+		}
+		// Dropping caches in the middle of a step doesn't change results.
+		if !equalCells(u.Cells(), bitwise.Cells()) {
+			t.Fatalf("engines differ at generation %d", u.Stats().Generation)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package universe
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Cell states of a multistate engine, dying states follow alive.
+const (
+	deadState  uint8 = 0
+	aliveState uint8 = 1
+)
+
+// multiStateEngine is an engine whose cells have more states than alive & dead
+type multiStateEngine interface {
+	engine
+	// states returns a matrix of cell states, rows first, 0 is dead, 1 alive, higher ones are dying.
+	states() [][]uint8
+	// appendStates appends states of cells, rows first, to dst.
+	appendStates(dst []uint8) []uint8
+	// setStates replaces states of all cells.
+	setStates(states [][]uint8) error
+	// shape returns a translation invariant hash of non-dead cells & their
+	// states, and the top left corner of their bounding box.
+	shape() (hash uint64, x, y int)
+}
+
+// multistateEngine evolves cells of Generations & Larger than Life rules one by one
+// Only alive cells count as neighbours, dying cells can't be born again
+// before they're dead.
+type multistateEngine struct {
+	rule     Rule
+	topology Topology
+	width    int
+	height   int
+	// sums counts neighbours of Larger than Life rules, nil for Moore ones.
+	sums *summedAreaTable
+	// transitions looks up next states of cells of Moore rules on the square grid.
+	transitions *transitions
+	grid        Grid
+	// cur & next hold states of cells, rows first.
+	cur  []uint8
+	next []uint8
+}
+
+// newMultistateEngine creates a multistateEngine from alive cells
+func newMultistateEngine(cells [][]bool, rule Rule, topology Topology, grid Grid) (engine, error) {
+	if _, ok := topology.(Unbounded); ok {
+		return nil, fmt.Errorf("multistate engine can't evolve %q topology", topology.Name())
+	}
+	height, width := len(cells), len(cells[0])
+	if !isSquare(grid) && (rule.Extended != nil || rule.Isotropic != nil) {
+		return nil, fmt.Errorf("%s grid supports B/S & Generations rules only", grid.Name())
+	}
+	e := &multistateEngine{
+		rule:     rule,
+		topology: topology,
+		grid:     grid,
+		width:    width,
+		height:   height,
+		cur:      make([]uint8, width*height),
+		next:     make([]uint8, width*height),
+	}
+	if rule.Extended != nil {
+		e.sums = newSummedAreaTable(rule.Extended.Neighbourhood, rule.Extended.Middle, width, height, topology)
+	} else {
+		e.transitions = rule.transitions()
+	}
+	for y, row := range cells {
+		for x, alive := range row {
+			if alive {
+				e.cur[y*width+x] = aliveState
+			}
+		}
+	}
+	return e, nil
+}
+
+// setStates replaces states of all cells
+func (e *multistateEngine) setStates(states [][]uint8) error {
+	if len(states) != e.height {
+		return fmt.Errorf("states have %d rows, expected %d", len(states), e.height)
+	}
+	maxState := 1
+	if e.rule.IsGenerations() {
+		maxState = e.rule.States - 1
+	}
+	for y, row := range states {
+		if len(row) != e.width {
+			return fmt.Errorf("states row %d has %d cells, expected %d", y, len(row), e.width)
+		}
+		for x, state := range row {
+			if int(state) > maxState {
+				return fmt.Errorf("state of cell (%d, %d) is %d, %d at most", x, y, state, maxState)
+			}
+		}
+	}
+	for y, row := range states {
+		copy(e.cur[y*e.width:], row)
+	}
+	return nil
+}
+
+// step advances cells generation by generation
+func (e *multistateEngine) step(exponent uint) {
+	for i := 0; i < 1<<exponent; i++ {
+		e.nextGeneration()
+	}
+}
+
+// maxStep returns the largest supported step exponent, evolving is linear so no jumps are allowed
+func (e *multistateEngine) maxStep() uint {
+	return 0
+}
+
+// nextGeneration computes the next generation
+func (e *multistateEngine) nextGeneration() {
+	if e.sums != nil {
+		e.sums.update(e.cur, aliveState)
+	}
+	for y := 0; y < e.height; y++ {
+		for x := 0; x < e.width; x++ {
+			i := y*e.width + x
+			switch state := e.cur[i]; state {
+			case deadState:
+				e.next[i] = deadState
+				if e.lives(x, y, false) {
+					e.next[i] = aliveState
+				}
+			case aliveState:
+				e.next[i] = aliveState
+				if !e.lives(x, y, true) {
+					e.next[i] = e.decay(state)
+				}
+			default:
+				e.next[i] = e.decay(state)
+			}
+		}
+	}
+	e.cur, e.next = e.next, e.cur
+}
+
+// decay returns the state following a dying or an alive cell failing to survive
+func (e *multistateEngine) decay(state uint8) uint8 {
+	if int(state)+1 >= e.rule.States {
+		return deadState
+	}
+	return state + 1
+}
+
+// lives tells if a dead or an alive cell is alive in the next generation
+func (e *multistateEngine) lives(x, y int, alive bool) bool {
+	if e.sums != nil {
+		ranges := e.rule.Extended.Birth
+		if alive {
+			ranges = e.rule.Extended.Survival
+		}
+		return ranges.contains(e.sums.count(x, y))
+	}
+	if !isSquare(e.grid) {
+		table := &e.rule.Birth
+		if alive {
+			table = &e.rule.Survival
+		}
+		count := e.neighboursCount(x, y)
+		return count <= maxNeighbours && table[count]
+	}
+	return e.transitions.next(alive, e.configuration(x, y))
+}
+
+// neighboursCount calculates the number of alive neighbours of a cell on the grid
+// Neighbours beyond the edges are looked up through the topology.
+func (e *multistateEngine) neighboursCount(x, y int) int {
+	var count int
+	for _, offset := range e.grid.Neighbours(x, y) {
+		nx, ny, ok := e.topology.Resolve(x+offset[0], y+offset[1], e.width, e.height)
+		if ok && e.cur[ny*e.width+nx] == aliveState {
+			count++
+		}
+	}
+	return count
+}
+
+// configuration calculates the configuration of alive Moore neighbours of a cell
+// Neighbours beyond the edges are looked up through the topology.
+func (e *multistateEngine) configuration(x, y int) uint8 {
+	interior := x > 0 && y > 0 && x < e.width-1 && y < e.height-1
+	var config uint8
+	for i, offset := range ringOffsets {
+		nx, ny := x+offset[0], y+offset[1]
+		if !interior {
+			var ok bool
+			if nx, ny, ok = e.topology.Resolve(nx, ny, e.width, e.height); !ok {
+				continue
+			}
+		}
+		if e.cur[ny*e.width+nx] == aliveState {
+			config |= 1 << i
+		}
+	}
+	return config
+}
+
+// cell tells if the cell at (x, y) is alive
+func (e *multistateEngine) cell(x, y int) bool {
+	return e.cur[y*e.width+x] == aliveState
+}
+
+// cells returns a matrix of alive cells
+func (e *multistateEngine) cells() [][]bool {
+	matrix := make([][]bool, e.height)
+	for y := range matrix {
+		matrix[y] = make([]bool, e.width)
+		for x := range matrix[y] {
+			matrix[y][x] = e.cur[y*e.width+x] == aliveState
+		}
+	}
+	return matrix
+}
+
+// states returns a copy of cell states
+func (e *multistateEngine) states() [][]uint8 {
+	matrix := make([][]uint8, e.height)
+	for y := range matrix {
+		matrix[y] = append([]uint8(nil), e.cur[y*e.width:(y+1)*e.width]...)
+	}
+	return matrix
+}
+
+// appendStates appends cell states to dst
+func (e *multistateEngine) appendStates(dst []uint8) []uint8 {
+	return append(dst, e.cur...)
+}
+
+// aliveCells returns coordinates of alive cells, rows first
+func (e *multistateEngine) aliveCells() [][2]int {
+	var cells [][2]int
+	for i, state := range e.cur {
+		if state == aliveState {
+			cells = append(cells, [2]int{i % e.width, i / e.width})
+		}
+	}
+	return cells
+}
+
+// population returns the number of alive cells
+func (e *multistateEngine) population() int {
+	var count int
+	for _, state := range e.cur {
+		if state == aliveState {
+			count++
+		}
+	}
+	return count
+}
+
+// hash calculates a hash value of cell states
+func (e *multistateEngine) hash() uint64 {
+	hasher := fnv.New64a()
+	hasher.Write(e.cur)
+	return hasher.Sum64()
+}
+
+// shape hashes states of non-dead cells relative to the top left corner of their bounding box
+// On a torus the box may straddle the seam, cells are hashed from its corner on.
+func (e *multistateEngine) shape() (uint64, int, int) {
+	columns, rows := make([]bool, e.width), make([]bool, e.height)
+	empty := true
+	for i, state := range e.cur {
+		if state != deadState {
+			columns[i%e.width], rows[i/e.width] = true, true
+			empty = false
+		}
+	}
+	if empty {
+		return 0, 0, 0
+	}
+	var minX, minY int
+	if _, ok := e.topology.(Torus); ok {
+		minX, minY = seamStart(columns), seamStart(rows)
+	} else {
+		for !columns[minX] {
+			minX++
+		}
+		for !rows[minY] {
+			minY++
+		}
+	}
+	// Shapes moved to cells of another parity have other neighbours.
+	hash := mixHash(uint64(e.rule.States), uint64(e.grid.Parity(minX, minY)))
+	for dy := 0; dy < e.height; dy++ {
+		row := (minY + dy) % e.height * e.width
+		for dx := 0; dx < e.width; dx++ {
+			if state := e.cur[row+(minX+dx)%e.width]; state != deadState {
+				hash = mixHash(hash, uint64(dx), uint64(dy), uint64(state))
+			}
+		}
+	}
+	// 0 is reserved for an unknown shape.
+	if hash == 0 {
+		hash = 1
+	}
+	return hash, minX, minY
+}
@@ -0,0 +1,151 @@
+package universe
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// scalarEngine evolves cells one by one in a matrix of booleans
+// It's the original engine, kept as a reference implementation and a benchmark baseline.
+// Next states are looked up by configurations of neighbours, so it runs
+// isotropic non-totalistic rules as well.
+type scalarEngine struct {
+	matrix      [][]bool
+	rule        Rule
+	transitions *transitions
+	topology    Topology
+}
+
+// newScalarEngine creates a scalarEngine owning a copy of cells
+func newScalarEngine(cells [][]bool, rule Rule, topology Topology, grid Grid) (engine, error) {
+	if err := checkSquare("scalar", grid); err != nil {
+		return nil, err
+	}
+	if err := checkTwoStateMoore("scalar", rule); err != nil {
+		return nil, err
+	}
+	if _, ok := topology.(Unbounded); ok {
+		return nil, fmt.Errorf("scalar engine can't evolve %q topology", topology.Name())
+	}
+	matrix := make([][]bool, len(cells))
+	for y := range cells {
+		matrix[y] = make([]bool, len(cells[y]))
+		copy(matrix[y], cells[y])
+	}
+	return &scalarEngine{matrix: matrix, rule: rule, transitions: rule.transitions(), topology: topology}, nil
+}
+
+// step advances cells generation by generation
+func (e *scalarEngine) step(exponent uint) {
+	for i := 0; i < 1<<exponent; i++ {
+		e.nextGeneration()
+	}
+}
+
+// maxStep returns the largest supported step exponent, evolving is linear so no jumps are allowed
+func (e *scalarEngine) maxStep() uint {
+	return 0
+}
+
+// nextGeneration computes the next generation
+func (e *scalarEngine) nextGeneration() {
+	// Create a second matrix which represents a next generation.
+	var nextGenMatrix [][]bool
+	nextGenMatrix = make([][]bool, len(e.matrix))
+	for i := range nextGenMatrix {
+		nextGenMatrix[i] = make([]bool, len(e.matrix[i]))
+		copy(nextGenMatrix[i], e.matrix[i])
+	}
+
+	// Run game of live algorithm.
+	for y := range e.matrix {
+		for x := range e.matrix[y] {
+			nextGenMatrix[y][x] = e.transitions.next(e.matrix[y][x], e.configuration(x, y))
+		}
+	}
+	e.matrix = nextGenMatrix
+}
+
+// mooreOffsets lists relative coordinates of the 8 Moore neighbours.
+var mooreOffsets = [maxNeighbours][2]int{
+	{-1, -1}, {0, -1}, {1, -1},
+	{-1, 0}, {1, 0},
+	{-1, 1}, {0, 1}, {1, 1},
+}
+
+// configuration method calculates the configuration of live neighbors for a given cell.
+// Neighbours beyond the edges are looked up through the topology.
+func (e *scalarEngine) configuration(x, y int) uint8 {
+	height, width := len(e.matrix), len(e.matrix[y])
+	interior := x > 0 && y > 0 && x < width-1 && y < height-1
+	var config uint8
+	for i, offset := range ringOffsets {
+		nx, ny := x+offset[0], y+offset[1]
+		if !interior {
+			var ok bool
+			if nx, ny, ok = e.topology.Resolve(nx, ny, width, height); !ok {
+				continue
+			}
+		}
+		if e.matrix[ny][nx] == aliveValue {
+			config |= 1 << i
+		}
+	}
+	return config
+}
+
+// cell tells if the cell at (x, y) is alive
+func (e *scalarEngine) cell(x, y int) bool {
+	return e.matrix[y][x]
+}
+
+// cells returns a copy of the matrix
+func (e *scalarEngine) cells() [][]bool {
+	matrix := make([][]bool, len(e.matrix))
+	for y := range e.matrix {
+		matrix[y] = make([]bool, len(e.matrix[y]))
+		copy(matrix[y], e.matrix[y])
+	}
+	return matrix
+}
+
+// aliveCells returns coordinates of alive cells, rows first
+func (e *scalarEngine) aliveCells() [][2]int {
+	var cells [][2]int
+	for y := range e.matrix {
+		for x, cell := range e.matrix[y] {
+			if cell == aliveValue {
+				cells = append(cells, [2]int{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// population returns the number of alive cells
+func (e *scalarEngine) population() int {
+	var count int
+	for _, row := range e.matrix {
+		for _, cell := range row {
+			if cell == aliveValue {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// hash calculates a hash value of the matrix
+func (e *scalarEngine) hash() uint64 {
+	hasher := fnv.New64a()
+	for y := range e.matrix {
+		for x := range e.matrix[y] {
+			if e.matrix[y][x] {
+				hasher.Write([]byte{1})
+			} else {
+				hasher.Write([]byte{0})
+			}
+		}
+	}
+	return hasher.Sum64()
+}
@@ -0,0 +1,94 @@
+package universe
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomCells creates a width x height matrix of randomly alive cells
+func randomCells(width, height int, density float64, seed int64) [][]bool {
+	rng := rand.New(rand.NewSource(seed))
+	cells := make([][]bool, height)
+	for y := range cells {
+		cells[y] = make([]bool, width)
+		for x := range cells[y] {
+			cells[y][x] = rng.Float64() < density
+		}
+	}
+	return cells
+}
+
+func TestBitwiseMatchesScalar(t *testing.T) {
+	rules := []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B1357/S1357", "B0123478/S01234678"}
+	for name, topology := range topologies {
+		if _, ok := topology.(Unbounded); ok {
+			// Neither engine evolves an infinite plane.
+			for _, engine := range []string{"bitwise", "scalar"} {
+				if _, err := New([][]bool{{true}}, Options{Rule: ConwayRule, Topology: topology, Engine: engine}); err == nil {
+					t.Errorf("%s engine accepted %s topology", engine, name)
+				}
+			}
+			continue
+		}
+		for _, width := range []int{1, 63, 64, 65, 130} {
+			for _, height := range []int{1, 2, 37} {
+				for i, rule := range rules {
+					cells := randomCells(width, height, 0.4, int64(width*1000+height*10+i))
+					opts := Options{Rule: MustParseRule(rule), Topology: topology}
+					opts.Engine = "bitwise"
+					bitwise, err := New(cells, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					opts.Engine = "scalar"
+					scalar, err := New(cells, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for generation := 1; generation <= 20; generation++ {
+						bitwise.Evolve()
+						scalar.Evolve()
+						if !equalCells(bitwise.Cells(), scalar.Cells()) {
+							t.Fatalf("%s %dx%d %s: engines differ at generation %d", name, width, height, rule, generation)
+						}
+						if bitwise.Stats().Alive != scalar.Stats().Alive {
+							t.Fatalf("%s %dx%d %s: populations differ at generation %d", name, width, height, rule, generation)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// benchmarkEngine evolves random soups of several sizes b.N generations with the given engine
+// Soups are recreated every 200 generations, so an already static universe is never measured.
+func benchmarkEngine(b *testing.B, engine string) {
+	for _, size := range []int{50, 200, 1000} {
+		cells := randomCells(size, size, 0.35, 1)
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			b.ReportAllocs()
+			var u *Universe
+			for i := 0; i < b.N; i++ {
+				if i%200 == 0 {
+					b.StopTimer()
+					var err error
+					if u, err = New(cells, Options{Rule: ConwayRule, Engine: engine}); err != nil {
+						b.Fatal(err)
+					}
+					b.StartTimer()
+				}
+				u.Evolve()
+			}
+		})
+	}
+}
+
+func BenchmarkBitwise(b *testing.B) {
+	benchmarkEngine(b, "bitwise")
+}
+
+func BenchmarkScalar(b *testing.B) {
+	benchmarkEngine(b, "scalar")
+}
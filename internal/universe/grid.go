@@ -0,0 +1,148 @@
+package universe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Grid describes the shape of cells and which of them are neighbours
+// Cells are addressed by (x, y) on every grid, rows of hexagons are offset
+// and triangles alternate their orientation.
+type Grid interface {
+	// Name returns the name the grid is registered under.
+	Name() string
+	// Neighbours returns offsets of neighbours of the cell at (x, y).
+	Neighbours(x, y int) [][2]int
+	// Parity returns the class of the cell at (x, y), cells of a class share
+	// offsets of neighbours, so shapes only repeat when moved within a class.
+	Parity(x, y int) int
+	// DefaultRule is used when a universe on the grid doesn't specify a rule.
+	DefaultRule() Rule
+}
+
+// DefaultGrid is used when no grid is given.
+var DefaultGrid Grid = Square{}
+
+// grids holds all known grids by name.
+var grids = map[string]Grid{}
+
+// gridAliases maps alternative names to registered grid names.
+var gridAliases = map[string]string{
+	"hexagonal":   "hex",
+	"triangle":    "triangular",
+	"triangles":   "triangular",
+	"rectangular": "square",
+}
+
+func init() {
+	for _, g := range []Grid{Square{}, Hex{}, Triangular{}} {
+		RegisterGrid(g)
+	}
+}
+
+// RegisterGrid makes a grid available by its name
+func RegisterGrid(g Grid) {
+	grids[g.Name()] = g
+}
+
+// ParseGrid finds a registered grid by its name or alias
+func ParseGrid(name string) (Grid, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := gridAliases[name]; ok {
+		name = alias
+	}
+	g, ok := grids[name]
+	if !ok {
+		names := make([]string, 0, len(grids))
+		for n := range grids {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown grid %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return g, nil
+}
+
+// isSquare tells if the grid is made of squares with 8 Moore neighbours
+func isSquare(g Grid) bool {
+	_, ok := g.(Square)
+	return ok
+}
+
+// checkSquare fails for grids other than the square one
+func checkSquare(engine string, g Grid) error {
+	if !isSquare(g) {
+		return fmt.Errorf("%s engine doesn't support %q grid, use multistate engine", engine, g.Name())
+	}
+	return nil
+}
+
+// Square is the classic grid of squares with 8 Moore neighbours
+type Square struct{}
+
+// Name returns the name of the grid
+func (Square) Name() string { return "square" }
+
+// Neighbours returns offsets of the 8 Moore neighbours
+func (Square) Neighbours(x, y int) [][2]int { return ringOffsets[:] }
+
+// Parity returns the class of a cell, all squares are alike
+func (Square) Parity(x, y int) int { return 0 }
+
+// DefaultRule returns Conway's rule
+func (Square) DefaultRule() Rule { return ConwayRule }
+
+// hexOffsets lists neighbours of hexagons of even & odd rows, odd rows are shifted right by half a cell.
+var hexOffsets = [2][][2]int{
+	{{-1, -1}, {0, -1}, {1, 0}, {0, 1}, {-1, 1}, {-1, 0}},
+	{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 0}},
+}
+
+// Hex is a grid of pointy-top hexagons with 6 neighbours in offset coordinates
+// Wrapping edges interlock seamlessly only with an even number of rows.
+type Hex struct{}
+
+// Name returns the name of the grid
+func (Hex) Name() string { return "hex" }
+
+// Neighbours returns offsets of the 6 neighbours, they depend on the row
+func (Hex) Neighbours(x, y int) [][2]int { return hexOffsets[floorMod(y, 2)] }
+
+// Parity returns the class of a cell, even or odd row
+func (Hex) Parity(x, y int) int { return floorMod(y, 2) }
+
+// DefaultRule returns the hexagonal Life rule
+func (Hex) DefaultRule() Rule { return MustParseRule("B2/S34") }
+
+// triangleOffsets lists neighbours sharing an edge or a corner with up & down pointing triangles.
+var triangleOffsets = [2][][2]int{
+	{
+		{-1, -1}, {0, -1}, {1, -1},
+		{-2, 0}, {-1, 0}, {1, 0}, {2, 0},
+		{-2, 1}, {-1, 1}, {0, 1}, {1, 1}, {2, 1},
+	},
+	{
+		{-2, -1}, {-1, -1}, {0, -1}, {1, -1}, {2, -1},
+		{-2, 0}, {-1, 0}, {1, 0}, {2, 0},
+		{-1, 1}, {0, 1}, {1, 1},
+	},
+}
+
+// Triangular is a grid of triangles with 12 neighbours sharing an edge or a corner
+// Triangles at even x+y point up, others point down. Counts of neighbours
+// above 8 can't be given in B/S notation, so they never give birth nor survive.
+// Wrapping edges interlock seamlessly only with even sizes.
+type Triangular struct{}
+
+// Name returns the name of the grid
+func (Triangular) Name() string { return "triangular" }
+
+// Neighbours returns offsets of the 12 neighbours, they depend on the orientation
+func (Triangular) Neighbours(x, y int) [][2]int { return triangleOffsets[floorMod(x+y, 2)] }
+
+// Parity returns the class of a cell, pointing up or down
+func (Triangular) Parity(x, y int) int { return floorMod(x+y, 2) }
+
+// DefaultRule returns a triangular Life-like rule
+func (Triangular) DefaultRule() Rule { return MustParseRule("B4/S345") }
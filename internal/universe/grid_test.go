@@ -0,0 +1,90 @@
+package universe
+
+import "testing"
+
+func TestGridNeighbours(t *testing.T) {
+	tests := []struct {
+		grid Grid
+		want int
+	}{
+		{Square{}, 8},
+		{Hex{}, 6},
+		{Triangular{}, 12},
+	}
+	for _, tt := range tests {
+		// Cells of all parities, also at negative coordinates.
+		for y := -2; y < 2; y++ {
+			for x := -2; x < 2; x++ {
+				offsets := tt.grid.Neighbours(x, y)
+				seen := map[[2]int]bool{}
+				for _, o := range offsets {
+					if o == [2]int{0, 0} || seen[o] {
+						t.Errorf("%s: cell (%d, %d) has offset %v twice or itself", tt.grid.Name(), x, y, o)
+					}
+					seen[o] = true
+					// Neighbours are mutual.
+					nx, ny := x+o[0], y+o[1]
+					mutual := false
+					for _, back := range tt.grid.Neighbours(nx, ny) {
+						mutual = mutual || nx+back[0] == x && ny+back[1] == y
+					}
+					if !mutual {
+						t.Errorf("%s: (%d, %d) neighbours (%d, %d) but not the other way round", tt.grid.Name(), x, y, nx, ny)
+					}
+				}
+				if len(offsets) != tt.want {
+					t.Errorf("%s: cell (%d, %d) has %d neighbours, want %d", tt.grid.Name(), x, y, len(offsets), tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestHexEvolution(t *testing.T) {
+	// Under B2/S two cells of an even row give birth to the two cells of odd
+	// rows touching both of them & die, those give birth to them again.
+	pair := parseCells(".....", ".....", ".oo..", ".....", ".....")
+	u, err := New(pair, Options{Rule: MustParseRule("B2/S"), Grid: Hex{}, Topology: Bounded{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Evolve()
+	if want := parseCells(".....", ".o...", ".....", ".o...", "....."); !equalCells(u.Cells(), want) {
+		t.Errorf("pair evolved into %v, want %v", u.Cells(), want)
+	}
+	u.Evolve()
+	if !equalCells(u.Cells(), pair) {
+		t.Errorf("pair didn't come back: %v", u.Cells())
+	}
+	settle(u, 10)
+	if u.Period != 2 {
+		t.Errorf("hexagonal oscillator has period %d, want 2", u.Period)
+	}
+}
+
+func TestTriangularEvolution(t *testing.T) {
+	// Under B1/S a triangle dies & gives birth to its 12 neighbours: 3 beyond
+	// its apex & 5 beyond its base, 4 sharing its row.
+	tests := []struct {
+		name  string
+		cells [][]bool
+		want  [][]bool
+	}{
+		{
+			"up",
+			parseCells("......", "......", "..o...", "......", "......"),
+			parseCells("......", ".ooo..", "oo.oo.", "ooooo.", "......"),
+		},
+		{
+			"down",
+			parseCells("......", "......", "...o..", "......", "......"),
+			parseCells("......", ".ooooo", ".oo.oo", "..ooo.", "......"),
+		},
+	}
+	for _, tt := range tests {
+		got := evolved(t, tt.cells, Options{Rule: MustParseRule("B1/S"), Grid: Triangular{}, Topology: Bounded{}}, 1)
+		if !equalCells(got, tt.want) {
+			t.Errorf("%s triangle evolved into %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
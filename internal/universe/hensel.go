@@ -0,0 +1,225 @@
+package universe
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// ringOffsets lists relative coordinates of the 8 Moore neighbours clockwise
+// from the north one, the i-th neighbour is the i-th bit of a configuration.
+var ringOffsets = [maxNeighbours][2]int{
+	{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1},
+}
+
+// henselLetters lists letters of configurations of 0 to 4 alive neighbours in
+// canonical order, 5 to 8 neighbours use letters of complements.
+var henselLetters = [maxNeighbours/2 + 1]string{"", "ce", "ceaikn", "ceaiknjqry", "ceaiknjqrytwz"}
+
+// henselConfigurations holds a configuration of each letter of 1 to 4 alive
+// neighbours, bits follow ringOffsets: N, NE, E, SE, S, SW, W, NW.
+var henselConfigurations = map[string]uint8{
+	"1c": 0b00000010, "1e": 0b00000001,
+	"2c": 0b00001010, "2e": 0b00000101, "2k": 0b00001001,
+	"2a": 0b00000011, "2i": 0b00010001, "2n": 0b00100010,
+	"3c": 0b00101010, "3e": 0b00010101, "3k": 0b00100101, "3a": 0b00000111, "3i": 0b10000011,
+	"3n": 0b00001011, "3y": 0b00101001, "3q": 0b00100011, "3j": 0b01000011, "3r": 0b00010011,
+	"4c": 0b10101010, "4e": 0b01010101, "4k": 0b01001011, "4a": 0b00001111, "4i": 0b00011011,
+	"4n": 0b10001011, "4y": 0b00101011, "4q": 0b00100111, "4j": 0b01010011, "4r": 0b00010111,
+	"4t": 0b10010011, "4w": 0b01100011, "4z": 0b00110011,
+}
+
+// henselLetterOf holds the letter of each configuration, 0 for 0 & 8 alive neighbours.
+var henselLetterOf [256]byte
+
+func init() {
+	for key, config := range henselConfigurations {
+		count, letter := int(key[0]-'0'), key[1]
+		for _, c := range symmetries(config) {
+			henselLetterOf[c] = letter
+			if count < maxNeighbours/2 {
+				henselLetterOf[^c] = letter
+			}
+		}
+	}
+}
+
+// symmetries returns all rotations & reflections of a configuration
+func symmetries(config uint8) []uint8 {
+	configs := make([]uint8, 0, 8)
+	for r := 0; r < 4; r++ {
+		// Rotating by 90 degrees moves each neighbour 2 places clockwise.
+		rotated := bits.RotateLeft8(config, 2*r)
+		// Reflecting about the north-south axis maps the i-th neighbour to the (8-i)-th one.
+		var reflected uint8
+		for i := 0; i < maxNeighbours; i++ {
+			if rotated&(1<<i) != 0 {
+				reflected |= 1 << ((maxNeighbours - i) % maxNeighbours)
+			}
+		}
+		configs = append(configs, rotated, reflected)
+	}
+	return configs
+}
+
+// lettersOf returns letters of configurations of the given number of alive neighbours
+func lettersOf(count int) string {
+	if count > maxNeighbours/2 {
+		count = maxNeighbours - count
+	}
+	return henselLetters[count]
+}
+
+// Configurations tells which configurations of the 8 neighbours give birth
+// or let a cell survive, bits of a configuration are neighbours clockwise from
+// the north one
+type Configurations struct {
+	Birth    [256]bool
+	Survival [256]bool
+}
+
+// isHensel tells if birth or survival counts are followed by letters
+func isHensel(birth, survival string) bool {
+	return strings.IndexFunc(birth+survival, func(c rune) bool { return c < '0' || c > '9' }) >= 0
+}
+
+// parseHensel parses birth & survival of an isotropic non-totalistic rule,
+// e.g. "2-a" & "12". Rules with all or none of the configurations of each count
+// end up in B/S tables.
+func parseHensel(birth, survival string, rule *Rule) error {
+	var configs Configurations
+	if err := parseHenselCounts(birth, &configs.Birth); err != nil {
+		return fmt.Errorf("birth: %w", err)
+	}
+	if err := parseHenselCounts(survival, &configs.Survival); err != nil {
+		return fmt.Errorf("survival: %w", err)
+	}
+	totalistic := true
+	for config := 0; config < 256; config++ {
+		count := bits.OnesCount8(uint8(config))
+		rule.Birth[count] = rule.Birth[count] || configs.Birth[config]
+		rule.Survival[count] = rule.Survival[count] || configs.Survival[config]
+	}
+	for config := 0; config < 256; config++ {
+		count := bits.OnesCount8(uint8(config))
+		if rule.Birth[count] != configs.Birth[config] || rule.Survival[count] != configs.Survival[config] {
+			totalistic = false
+		}
+	}
+	if !totalistic {
+		// Birth & Survival tell if any configuration of a count gives birth or survives.
+		rule.Isotropic = &configs
+	}
+	return nil
+}
+
+// parseHenselCounts fills a configurations table from counts optionally
+// followed by letters of included ("3ai") or excluded ("2-a") configurations
+func parseHenselCounts(s string, table *[256]bool) error {
+	var seen [maxNeighbours + 1]bool
+	for i := 0; i < len(s); {
+		d := s[i]
+		if d < '0' || d > '0'+maxNeighbours {
+			return fmt.Errorf("invalid neighbours count %q", d)
+		}
+		count := int(d - '0')
+		if seen[count] {
+			return fmt.Errorf("duplicated neighbours count %q", d)
+		}
+		seen[count] = true
+		i++
+		exclude := i < len(s) && s[i] == '-'
+		if exclude {
+			i++
+		}
+		start := i
+		for i < len(s) && (s[i] < '0' || s[i] > '9') {
+			i++
+		}
+		letters := s[start:i]
+		for _, l := range letters {
+			if !strings.ContainsRune(lettersOf(count), l) {
+				return fmt.Errorf("invalid configuration %d%c, expected one of %q", count, l, lettersOf(count))
+			}
+		}
+		if exclude && letters == "" {
+			return fmt.Errorf("missing configurations after %d-", count)
+		}
+		for config := 0; config < 256; config++ {
+			if bits.OnesCount8(uint8(config)) != count {
+				continue
+			}
+			listed := strings.IndexByte(letters, henselLetterOf[config]) >= 0
+			table[config] = letters == "" || listed != exclude
+		}
+	}
+	return nil
+}
+
+// henselCounts returns counts & letters of configurations set in the table, e.g. "2-a3ai"
+func henselCounts(table *[256]bool) string {
+	var sb strings.Builder
+	for count := 0; count <= maxNeighbours; count++ {
+		var included, excluded []byte
+		for _, l := range []byte(lettersOf(count)) {
+			if henselSet(table, count, l) {
+				included = append(included, l)
+			} else {
+				excluded = append(excluded, l)
+			}
+		}
+		switch {
+		case lettersOf(count) == "":
+			if henselSet(table, count, 0) {
+				sb.WriteByte(byte('0' + count))
+			}
+		case len(included) == 0:
+		case len(excluded) == 0:
+			sb.WriteByte(byte('0' + count))
+		case len(included) <= len(excluded):
+			sb.WriteByte(byte('0' + count))
+			sb.Write(included)
+		default:
+			sb.WriteByte(byte('0' + count))
+			sb.WriteByte('-')
+			sb.Write(excluded)
+		}
+	}
+	return sb.String()
+}
+
+// henselSet tells if configurations of a count & letter are set in the table
+func henselSet(table *[256]bool, count int, letter byte) bool {
+	for config := 0; config < 256; config++ {
+		if bits.OnesCount8(uint8(config)) == count && henselLetterOf[config] == letter {
+			return table[config]
+		}
+	}
+	return false
+}
+
+// transitions tells if a cell is alive in the next generation, by whether it's
+// alive now & the configuration of its 8 neighbours
+type transitions [2][256]bool
+
+// transitions builds the lookup table of a rule over the 8 Moore neighbours
+func (r Rule) transitions() *transitions {
+	var t transitions
+	if r.Isotropic != nil {
+		t[0], t[1] = r.Isotropic.Birth, r.Isotropic.Survival
+		return &t
+	}
+	for config := 0; config < 256; config++ {
+		count := bits.OnesCount8(uint8(config))
+		t[0][config], t[1][config] = r.Birth[count], r.Survival[count]
+	}
+	return &t
+}
+
+// next tells if a cell with the given configuration of neighbours is alive in the next generation
+func (t *transitions) next(alive bool, config uint8) bool {
+	if alive {
+		return t[1][config]
+	}
+	return t[0][config]
+}
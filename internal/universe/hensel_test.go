@@ -0,0 +1,200 @@
+package universe
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestHenselLetters(t *testing.T) {
+	// Every configuration of 1 to 7 alive neighbours has a letter, all its
+	// rotations & reflections share it & letters of a count tell them apart.
+	classes := 0
+	for count := 1; count < maxNeighbours; count++ {
+		letters := map[byte]bool{}
+		for config := 0; config < 256; config++ {
+			if bits.OnesCount8(uint8(config)) != count {
+				continue
+			}
+			letter := henselLetterOf[config]
+			if letter == 0 {
+				t.Fatalf("configuration %08b has no letter", config)
+			}
+			for _, c := range symmetries(uint8(config)) {
+				if henselLetterOf[c] != letter {
+					t.Errorf("configuration %08b is %c, its symmetry %08b is %c", config, letter, c, henselLetterOf[c])
+				}
+			}
+			letters[letter] = true
+		}
+		if len(letters) != len(lettersOf(count)) {
+			t.Errorf("%d alive neighbours have %d letters, want %q", count, len(letters), lettersOf(count))
+		}
+		classes += len(letters)
+	}
+	// With 0 & 8 alive neighbours there are 51 configurations up to symmetry.
+	if classes+2 != 51 {
+		t.Errorf("%d configurations up to symmetry, want 51", classes+2)
+	}
+	// Those have no letter, counts above 4 take letters of complements.
+	if henselLetterOf[0] != 0 || henselLetterOf[255] != 0 {
+		t.Errorf("0 or 8 alive neighbours have a letter")
+	}
+	for config := 0; config < 256; config++ {
+		if count := bits.OnesCount8(uint8(config)); count != 4 && henselLetterOf[config] != henselLetterOf[^uint8(config)] {
+			t.Errorf("configuration %08b is %c, its complement %c", config, henselLetterOf[config], henselLetterOf[^uint8(config)])
+		}
+	}
+}
+
+func TestHenselChart(t *testing.T) {
+	// Neighbourhoods as drawn by the Hensel notation chart, the middle cell is x.
+	chart := []struct {
+		letter  string
+		picture [3]string
+	}{
+		{"1c", [3]string{"o..", ".x.", "..."}},
+		{"1e", [3]string{".o.", ".x.", "..."}},
+		// Corners of one side, edges around a corner & opposite ones.
+		{"2c", [3]string{"o.o", ".x.", "..."}},
+		{"2e", [3]string{".o.", "ox.", "..."}},
+		{"2k", [3]string{".o.", ".x.", "..o"}},
+		{"2a", [3]string{"oo.", ".x.", "..."}},
+		{"2i", [3]string{".o.", ".x.", ".o."}},
+		{"2n", [3]string{"o..", ".x.", "..o"}},
+		{"3c", [3]string{"o.o", ".x.", "o.."}},
+		{"3e", [3]string{".o.", "oxo", "..."}},
+		{"3k", [3]string{".o.", "ox.", "..o"}},
+		{"3a", [3]string{"oo.", "ox.", "..."}},
+		// A side in a line.
+		{"3i", [3]string{"o..", "ox.", "o.."}},
+		{"3n", [3]string{"o.o", "ox.", "..."}},
+		{"3y", [3]string{"o.o", ".x.", ".o."}},
+		{"3q", [3]string{"o..", "ox.", "..o"}},
+		{"3j", [3]string{"o..", "ox.", ".o."}},
+		{"3r", [3]string{".oo", ".x.", ".o."}},
+		{"4c", [3]string{"o.o", ".x.", "o.o"}},
+		{"4e", [3]string{".o.", "oxo", ".o."}},
+		{"4a", [3]string{"oo.", "ox.", "o.."}},
+		{"4q", [3]string{"oo.", "ox.", "..o"}},
+		{"4t", [3]string{"ooo", ".x.", ".o."}},
+		{"4w", [3]string{"o..", "ox.", ".oo"}},
+		{"4z", [3]string{"oo.", ".x.", ".oo"}},
+	}
+	for _, tt := range chart {
+		var config uint8
+		for i, offset := range ringOffsets {
+			if tt.picture[offset[1]+1][offset[0]+1] == 'o' {
+				config |= 1 << i
+			}
+		}
+		if got := henselLetterOf[config]; got != tt.letter[1] {
+			t.Errorf("%v is %c, want %s", tt.picture, got, tt.letter)
+		}
+		// Complements of 1 to 3 alive neighbours share their letters.
+		if tt.letter[0] < '4' && henselLetterOf[^config] != tt.letter[1] {
+			t.Errorf("complement of %s is %c", tt.letter, henselLetterOf[^config])
+		}
+	}
+}
+
+func TestParseHensel(t *testing.T) {
+	tests := []struct {
+		notation   string
+		want       string
+		totalistic bool
+	}{
+		{"B2-a/S12", "B2-a/S12", false},
+		{"B3/S2-i34q", "B3/S2-i34q", false},
+		{"b2ce/s", "B2ce/S", false},
+		{"B5-c/S", "B5-c/S", false},
+		// The shorter list of letters is written.
+		{"B3aeijnqry/S", "B3-ck/S", false},
+		{"B2-aceikn2/S", "", false},
+		// All letters of a count are the count alone.
+		{"B2aceikn/S23", "B2/S23", true},
+		{"B3-/S23", "", false},
+		{"B1c1e/S", "", false},
+		{"B0c/S", "", false},
+		{"B2x/S", "", false},
+		{"B4-w/S0", "B4-w/S0", false},
+		{"B36-k/S23", "B36-k/S23", false},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.notation)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseRule(%q) = %s, want an error", tt.notation, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRule(%q) failed: %s", tt.notation, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("ParseRule(%q) = %s, want %s", tt.notation, got, tt.want)
+		}
+		if (rule.Isotropic == nil) != tt.totalistic {
+			t.Errorf("ParseRule(%q) is totalistic: %t, want %t", tt.notation, rule.Isotropic == nil, tt.totalistic)
+		}
+	}
+}
+
+func TestHenselTransitions(t *testing.T) {
+	// 2e are two alive neighbours at edges next to each other, 2i opposite ones.
+	next := MustParseRule("B2e/S").transitions().next
+	for _, c := range symmetries(henselConfigurations["2e"]) {
+		if !next(false, c) {
+			t.Errorf("configuration %08b of B2e gave no birth", c)
+		}
+	}
+	for _, c := range symmetries(henselConfigurations["2i"]) {
+		if next(false, c) {
+			t.Errorf("configuration %08b of 2i gave birth by B2e", c)
+		}
+	}
+	if next(true, henselConfigurations["2e"]) {
+		t.Errorf("a cell survived B2e/S")
+	}
+
+	// B2e/S evolved: north & east neighbours give birth, north & south ones don't.
+	got := evolved(t, parseCells(".o.", "..o", "...", "...", ".o.", "...", ".o."), Options{
+		Rule:     MustParseRule("B2e/S"),
+		Topology: Bounded{},
+	}, 1)
+	want := parseCells("..o", ".o.", "...", "...", "...", "...", "...")
+	if !equalCells(got, want) {
+		t.Errorf("B2e/S evolved into %v, want %v", got, want)
+	}
+}
+
+func TestHenselOscillator(t *testing.T) {
+	// Under B2c/S two cells of a row give birth to the cells above & below
+	// the gap, which give birth to them again. The cell between them has 2i.
+	pair := parseCells(".....", ".....", ".o.o.", ".....", ".....")
+	turned := parseCells(".....", "..o..", ".....", "..o..", ".....")
+	opts := Options{Rule: MustParseRule("B2c/S"), Topology: Bounded{}}
+	if got := evolved(t, pair, opts, 1); !equalCells(got, turned) {
+		t.Errorf("B2c/S evolved into %v, want %v", got, turned)
+	}
+	if got := evolved(t, pair, opts, 2); !equalCells(got, pair) {
+		t.Errorf("B2c/S oscillator evolved into %v, want %v", got, pair)
+	}
+	// Under B2i/S only the cell between them is born.
+	opts.Rule = MustParseRule("B2i/S")
+	if got, want := evolved(t, pair, opts, 1), parseCells(".....", ".....", "..o..", ".....", "....."); !equalCells(got, want) {
+		t.Errorf("B2i/S evolved into %v, want %v", got, want)
+	}
+}
+
+func TestHenselIsIsotropic(t *testing.T) {
+	cells := randomCells(19, 19, 0.35, 7)
+	opts := Options{Rule: MustParseRule("B2-a3/S12-k3ai"), Topology: Torus{}}
+	got := evolved(t, cells, opts, 10)
+	if turned := evolved(t, rotated(cells), opts, 10); !equalCells(turned, rotated(got)) {
+		t.Errorf("rotated soup evolved differently")
+	}
+	if flipped := evolved(t, mirrored(cells), opts, 10); !equalCells(flipped, mirrored(got)) {
+		t.Errorf("mirrored soup evolved differently")
+	}
+}
@@ -0,0 +1,247 @@
+package universe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// history keeps recent generations of a Universe as compressed diffs
+// A generation is stored as a frame: states of cells of Generations rules, a
+// byte per cell, or alive cells packed 64 into a word of 8 bytes per row,
+// followed by a byte per cell holding its colour in multi-colour universes.
+// Only the current frame is kept whole, diffs turn it back into previous ones.
+type history struct {
+	// depth is the number of previous generations kept at most.
+	depth int
+	// frame & generation describe the current generation, frame is nil until it's recorded.
+	frame      []byte
+	generation int
+	// next & data are reused by every recorded generation, so frames aren't allocated while evolving.
+	next []byte
+	data []byte
+	// diffs is a ring buffer of count diffs, the oldest one at start.
+	diffs []historyDiff
+	start int
+	count int
+}
+
+// historyDiff turns a frame back into the previous one
+type historyDiff struct {
+	// generation is the generation of the previous frame.
+	generation int
+	// data holds gaps between changed cells as uvarints, each followed by the
+	// xor of the cell in both frames, frames which didn't change have no data.
+	data []byte
+}
+
+// newHistory creates a history keeping up to depth previous generations
+func newHistory(depth int) *history {
+	return &history{depth: depth, diffs: make([]historyDiff, depth)}
+}
+
+// push adds a diff to the previous generation, the oldest one is dropped when full
+func (h *history) push(d historyDiff) {
+	if h.count == h.depth {
+		h.diffs[h.start] = d
+		h.start = (h.start + 1) % h.depth
+		return
+	}
+	h.diffs[(h.start+h.count)%h.depth] = d
+	h.count++
+}
+
+// newest returns the i-th newest diff, 0 being the newest one
+func (h *history) newest(i int) historyDiff {
+	return h.diffs[(h.start+h.count-1-i)%h.depth]
+}
+
+// diffFrames appends the diff turning the frame next back into the frame previous to data
+// Frames are compared a word at a time, only changed bytes of changed words are visited.
+func diffFrames(previous, next, data []byte) []byte {
+	last := -1
+	i := 0
+	for ; i+8 <= len(previous); i += 8 {
+		changed := binary.LittleEndian.Uint64(previous[i:]) ^ binary.LittleEndian.Uint64(next[i:])
+		for changed != 0 {
+			b := bits.TrailingZeros64(changed) / 8
+			data = binary.AppendUvarint(data, uint64(i+b-last-1))
+			data = append(data, byte(changed>>(b*8)))
+			last = i + b
+			changed &^= 0xff << (b * 8)
+		}
+	}
+	for ; i < len(previous); i++ {
+		if previous[i] != next[i] {
+			data = binary.AppendUvarint(data, uint64(i-last-1))
+			data = append(data, previous[i]^next[i])
+			last = i
+		}
+	}
+	return data
+}
+
+// undo applies the diff to the frame, turning it into the previous one
+func (d historyDiff) undo(frame []byte) {
+	i, data := -1, d.data
+	for len(data) > 0 {
+		gap, n := binary.Uvarint(data)
+		i += int(gap) + 1
+		frame[i] ^= data[n]
+		data = data[n+1:]
+	}
+}
+
+// SetHistoryDepth makes the universe keep up to depth previous generations to rewind to
+// 0 keeps none, changing the depth forgets generations kept so far.
+func (r *Universe) SetHistoryDepth(depth int) {
+	switch {
+	case depth <= 0:
+		r.history = nil
+	case r.history == nil || r.history.depth != depth:
+		r.history = newHistory(depth)
+	}
+}
+
+// History returns the number of generations the universe can be rewound by
+func (r *Universe) History() int {
+	h := r.history
+	if h == nil || h.count == 0 || h.generation != r.generationNumber {
+		return 0
+	}
+	return h.generation - h.newest(h.count-1).generation
+}
+
+// Rewind turns the universe back by the given number of generations, nothing is changed on error
+// Jumping universes are turned back to the closest kept generation before. Period
+// detection restarts, only cells within the window of an unbounded plane are kept.
+func (r *Universe) Rewind(generations int) error {
+	if generations < 1 {
+		return fmt.Errorf("generations must be positive, got %d", generations)
+	}
+	if kept := r.History(); generations > kept {
+		return fmt.Errorf("can't rewind %d generations, %d generations are kept", generations, kept)
+	}
+
+	// Undo diffs down to the generation on a copy, the history shrinks only on success.
+	h := r.history
+	frame := append([]byte(nil), h.frame...)
+	generation, undone := h.generation, 0
+	for generation > r.generationNumber-generations {
+		d := h.newest(undone)
+		d.undo(frame)
+		generation = d.generation
+		undone++
+	}
+
+	opts := r.options()
+	cells, states, colours := r.unframe(frame)
+	opts.States, opts.Colours = states, colours
+	u, err := New(cells, opts)
+	if err != nil {
+		return err
+	}
+	h.count -= undone
+	h.frame, h.generation = frame, generation
+	u.ID = r.ID
+	u.Soup = r.Soup
+	u.Rewinds = r.Rewinds + 1
+	u.generationNumber = generation
+	u.history = h
+	*r = *u
+	return nil
+}
+
+// keepFrame records the frame of the current generation unless it's recorded already
+func (r *Universe) keepFrame() {
+	if h := r.history; h != nil && (h.frame == nil || h.generation != r.generationNumber) {
+		// The universe changed otherwise than by evolving, older generations don't lead to it.
+		h.frame, h.generation, h.count = r.appendFrame(h.frame[:0]), r.generationNumber, 0
+	}
+}
+
+// record adds the generation reached by evolving from the recorded frame to the history
+func (r *Universe) record(changed bool) {
+	h := r.history
+	if h == nil {
+		return
+	}
+	d := historyDiff{generation: h.generation}
+	if changed {
+		h.next = r.appendFrame(h.next[:0])
+		h.data = diffFrames(h.frame, h.next, h.data[:0])
+		if len(h.data) > 0 {
+			d.data = append([]byte(nil), h.data...)
+		}
+		h.frame, h.next = h.next, h.frame
+	}
+	h.push(d)
+	h.generation = r.generationNumber
+}
+
+// appendFrame appends states & colours of cells of the current generation to frame
+func (r *Universe) appendFrame(frame []byte) []byte {
+	se, ok := r.engine.(multiStateEngine)
+	switch pe, packed := r.engine.(packedEngine); {
+	case ok && r.Rule.IsGenerations():
+		frame = se.appendStates(frame)
+	case packed:
+		for _, word := range pe.packed() {
+			frame = binary.LittleEndian.AppendUint64(frame, word)
+		}
+	default:
+		words := (r.width + wordBits - 1) / wordBits
+		for y := 0; y < r.height; y++ {
+			for i := 0; i < words; i++ {
+				var word uint64
+				for x := i * wordBits; x < (i+1)*wordBits && x < r.width; x++ {
+					if r.engine.cell(x, y) {
+						word |= 1 << (x % wordBits)
+					}
+				}
+				frame = binary.LittleEndian.AppendUint64(frame, word)
+			}
+		}
+	}
+	if r.colours != nil {
+		for _, row := range r.colours.colours {
+			frame = append(frame, row...)
+		}
+	}
+	return frame
+}
+
+// unframe returns cells, states of a Generations universe & colours of a multi-colour one from a frame
+func (r *Universe) unframe(frame []byte) ([][]bool, [][]uint8, [][]uint8) {
+	cells := make([][]bool, r.height)
+	var states, colours [][]uint8
+	if r.colours != nil {
+		colours = make([][]uint8, r.height)
+	}
+	// stride is the number of bytes per row of cells.
+	stride := r.width
+	if r.Rule.IsGenerations() {
+		states = make([][]uint8, r.height)
+	} else {
+		stride = (r.width + wordBits - 1) / wordBits * 8
+	}
+	size := stride * r.height
+	for y := range cells {
+		cells[y] = make([]bool, r.width)
+		if states != nil {
+			states[y] = append([]uint8(nil), frame[y*r.width:(y+1)*r.width]...)
+			for x, state := range states[y] {
+				cells[y][x] = state == aliveState
+			}
+		} else {
+			row := frame[y*stride:]
+			for x := range cells[y] {
+				cells[y][x] = row[x/8]&(1<<(x%8)) != 0
+			}
+		}
+		if colours != nil {
+			colours[y] = append([]uint8(nil), frame[size+y*r.width:size+(y+1)*r.width]...)
+		}
+	}
+	return cells, states, colours
+}
@@ -0,0 +1,111 @@
+package universe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRewind(t *testing.T) {
+	species := make([][]uint8, 9)
+	for y := range species {
+		species[y] = make([]uint8, 70)
+		for x := range species[y] {
+			species[y][x] = uint8(x/3+y) % 4
+		}
+	}
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		// 70 columns spread over two words per row.
+		{"bitwise", Options{Rule: ConwayRule, Topology: Torus{}}},
+		{"scalar", Options{Rule: MustParseRule("B2-a/S12"), Topology: Bounded{}, Engine: "scalar"}},
+		{"generations", Options{Rule: MustParseRule("starwars"), Topology: Torus{}}},
+		{"larger than life", Options{Rule: MustParseRule("R2,C0,M1,S9..16,B7..11,NM"), Topology: Torus{}}},
+		{"multi-colour", Options{Rule: MustParseRule("quadlife"), Topology: Bounded{}, Colours: species}},
+	}
+	type snapshot struct {
+		cells   [][]bool
+		states  [][]uint8
+		colours [][]int
+	}
+	for _, tt := range tests {
+		u, err := New(randomCells(70, 9, 0.4, 5), tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		u.SetHistoryDepth(10)
+		take := func() snapshot {
+			s := snapshot{cells: u.Cells(), states: u.States()}
+			if u.colours != nil {
+				s.colours = u.colours.matrix()
+			}
+			return s
+		}
+		snapshots := []snapshot{take()}
+		for i := 0; i < 15; i++ {
+			u.Evolve()
+			snapshots = append(snapshots, take())
+		}
+		if got := u.History(); got != 10 {
+			t.Fatalf("%s: %d generations kept, want 10", tt.name, got)
+		}
+
+		for _, step := range []struct{ generations, want int }{{3, 12}, {7, 5}} {
+			if err = u.Rewind(step.generations); err != nil {
+				t.Fatalf("%s: %s", tt.name, err)
+			}
+			if u.generationNumber != step.want || !reflect.DeepEqual(take(), snapshots[step.want]) {
+				t.Errorf("%s: rewound to generation %d, cells differ from generation %d", tt.name, u.generationNumber, step.want)
+			}
+		}
+		if err = u.Rewind(1); err == nil || u.generationNumber != 5 {
+			t.Errorf("%s: rewound beyond the history", tt.name)
+		}
+		// The rewound universe evolves as it did before.
+		u.Evolve()
+		if !reflect.DeepEqual(take(), snapshots[6]) || u.Rewinds != 2 {
+			t.Errorf("%s: rewound universe evolved differently", tt.name)
+		}
+	}
+}
+
+func TestRewindErrors(t *testing.T) {
+	u, err := New(parseCells("...", "ooo", "..."), Options{Rule: ConwayRule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = u.Rewind(1); err == nil {
+		t.Errorf("universe without a history rewound")
+	}
+	u.SetHistoryDepth(2)
+	for i := 0; i < 3; i++ {
+		u.Evolve()
+	}
+	for _, generations := range []int{0, -1, 3} {
+		if err = u.Rewind(generations); err == nil {
+			t.Errorf("rewind by %d generations succeeded", generations)
+		}
+	}
+	// A changed rule restarts the history.
+	rule := MustParseRule("B36/S23")
+	if err = u.Apply(Patch{Rule: &rule}); err != nil {
+		t.Fatal(err)
+	}
+	if u.History() != 0 || u.Rewind(1) == nil {
+		t.Errorf("history of the old rule kept")
+	}
+}
+
+func BenchmarkHistory(b *testing.B) {
+	u, err := New(randomCells(1000, 1000, 0.35, 1), Options{Rule: ConwayRule})
+	if err != nil {
+		b.Fatal(err)
+	}
+	u.SetHistoryDepth(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		u.Evolve()
+	}
+}
@@ -0,0 +1,253 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ride90/game-of-life/internal/pattern"
+	"strings"
+)
+
+// universeJSON is the JSON representation of a Universe
+type universeJSON struct {
+	Kind     string   `json:"kind"`
+	Matrix   [][]bool `json:"cells,omitempty"`
+	Colour   string   `json:"colour"`
+	Rule     *Rule    `json:"rule"`
+	Topology string   `json:"topology"`
+	Grid     string   `json:"grid"`
+	Engine   string   `json:"engine"`
+	Step     uint     `json:"step"`
+	// Palette & colours of multi-colour universes, dead cells are -1.
+	Palette []string `json:"palette,omitempty"`
+	Colours [][]int  `json:"colours,omitempty"`
+	// States of cells of Generations universes: 0 is dead, 1 alive, higher ones are dying.
+	States [][]int `json:"states,omitempty"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	// Write only pattern, an alternative to cells. RLE is kept for clients predating
+	// other formats, a pattern's format is recognised unless it's given.
+	RLE     string      `json:"rle,omitempty"`
+	Pattern string      `json:"pattern,omitempty"`
+	Format  string      `json:"format,omitempty"`
+	Offset  *offsetJSON `json:"offset,omitempty"`
+	// Read only fields, ignored when decoding.
+	ID         uint64    `json:"id"`
+	Generation int       `json:"generation"`
+	Alive      int       `json:"alive"`
+	IsStatic   bool      `json:"static"`
+	Period     int       `json:"period"`
+	PeriodFrom int       `json:"period_from"`
+	Velocity   *Velocity `json:"velocity,omitempty"`
+	Converged  string    `json:"converged"`
+	// History is the number of generations the universe can be rewound by.
+	History int `json:"history"`
+	Rewinds int `json:"rewinds"`
+	// Soup the cells were generated from, to reproduce random universes.
+	Soup *Soup `json:"soup,omitempty"`
+}
+
+// offsetJSON places a pattern within a universe, missing coordinates are centred
+type offsetJSON struct {
+	X *int `json:"x"`
+	Y *int `json:"y"`
+}
+
+// MarshalJSON encodes the Universe for clients
+func (r *Universe) MarshalJSON() ([]byte, error) {
+	var velocity *Velocity
+	if !r.Velocity.IsZero() {
+		velocity = &r.Velocity
+	}
+	var colours [][]int
+	if r.colours != nil {
+		colours = r.colours.matrix()
+	}
+	var states [][]int
+	for _, row := range r.States() {
+		statesRow := make([]int, len(row))
+		for x, state := range row {
+			statesRow[x] = int(state)
+		}
+		states = append(states, statesRow)
+	}
+	return json.Marshal(universeJSON{
+		Kind:       r.Kind(),
+		ID:         r.ID,
+		Matrix:     r.Cells(),
+		Colour:     r.Colour,
+		Rule:       &r.Rule,
+		Topology:   r.Topology.Name(),
+		Grid:       r.Grid.Name(),
+		Engine:     r.Engine,
+		Step:       r.Step,
+		Palette:    r.Palette(),
+		Colours:    colours,
+		States:     states,
+		Width:      r.width,
+		Height:     r.height,
+		Generation: r.generationNumber,
+		Alive:      r.aliveCellsCount,
+		IsStatic:   r.IsStatic,
+		Period:     r.Period,
+		PeriodFrom: r.PeriodFrom,
+		Velocity:   velocity,
+		Converged:  r.Convergence(),
+		History:    r.History(),
+		Rewinds:    r.Rewinds,
+		Soup:       r.Soup,
+	})
+}
+
+// UnmarshalJSON decodes a Universe from either a cells matrix or a pattern
+// Without a rule it falls back to the pattern's rule or the grid's default one,
+// without a topology to the engine's default.
+func (r *Universe) UnmarshalJSON(data []byte) error {
+	return r.decode(data, nil, nil)
+}
+
+// decode decodes a Universe, defaultRule takes precedence over the pattern's
+// & the grid's rules if it's given, cells are generated from the soup if it's given
+func (r *Universe) decode(data []byte, defaultRule *Rule, soup *Soup) error {
+	var u universeJSON
+	if err := json.Unmarshal(data, &u); err != nil {
+		return err
+	}
+	grid := DefaultGrid
+	if u.Grid != "" {
+		var err error
+		if grid, err = ParseGrid(u.Grid); err != nil {
+			return err
+		}
+	}
+	if soup != nil {
+		if u.Matrix != nil || u.RLE != "" || u.Pattern != "" || u.States != nil || u.Colours != nil {
+			return fmt.Errorf("soups take no cells, patterns, states nor colours")
+		}
+		var err error
+		if u.Matrix, err = soup.cellsOn(grid); err != nil {
+			return err
+		}
+	}
+
+	decodePattern := pattern.Decode
+	if u.RLE != "" {
+		if u.Pattern != "" {
+			return fmt.Errorf("either rle or pattern must be given, not both")
+		}
+		u.Pattern, u.Format = u.RLE, "rle"
+	}
+	if u.Format != "" {
+		if u.Pattern == "" {
+			return fmt.Errorf("format needs a pattern")
+		}
+		format, err := pattern.ParseFormat(u.Format)
+		if err != nil {
+			return err
+		}
+		decodePattern = format.Decode
+	}
+
+	var rule Rule
+	matrix := u.Matrix
+	switch {
+	case u.Pattern != "" && u.Matrix != nil:
+		return fmt.Errorf("either cells or a pattern must be given, not both")
+	case u.Pattern != "":
+		p, err := decodePattern(u.Pattern)
+		if err != nil {
+			return err
+		}
+		if p.Rule != "" {
+			// Golly's bounded grid suffix (":T100,100") isn't supported, use topology instead.
+			notation, _, _ := strings.Cut(p.Rule, ":")
+			if rule, err = ParseRule(notation); err != nil {
+				return fmt.Errorf("pattern: %w", err)
+			}
+		}
+		width, height := u.Width, u.Height
+		if width == 0 {
+			width = p.Width
+		}
+		if height == 0 {
+			height = p.Height
+		}
+		var offsetX, offsetY *int
+		if u.Offset != nil {
+			offsetX, offsetY = u.Offset.X, u.Offset.Y
+		}
+		if matrix, err = p.Place(width, height, offsetX, offsetY); err != nil {
+			return err
+		}
+		if p.Rule == "" {
+			rule = grid.DefaultRule()
+		}
+	default:
+		rule = grid.DefaultRule()
+	}
+	if defaultRule != nil {
+		rule = *defaultRule
+	}
+	if u.Rule != nil {
+		rule = *u.Rule
+	}
+
+	var topology Topology
+	if u.Topology != "" {
+		var err error
+		if topology, err = ParseTopology(u.Topology); err != nil {
+			return err
+		}
+	}
+	var colours [][]uint8
+	if u.Colours != nil {
+		colours = make([][]uint8, len(u.Colours))
+		for y, row := range u.Colours {
+			colours[y] = make([]uint8, len(row))
+			for x, c := range row {
+				if c < -1 || c >= maxColours {
+					return fmt.Errorf("colour of cell (%d, %d) must be a palette index or -1, got %d", x, y, c)
+				}
+				if c > 0 {
+					colours[y][x] = uint8(c)
+				}
+			}
+		}
+	}
+	var states [][]uint8
+	if u.States != nil {
+		if matrix == nil {
+			// States alone describe cells.
+			matrix = make([][]bool, len(u.States))
+			for y, row := range u.States {
+				matrix[y] = make([]bool, len(row))
+			}
+		}
+		states = make([][]uint8, len(u.States))
+		for y, row := range u.States {
+			states[y] = make([]uint8, len(row))
+			for x, state := range row {
+				if state < 0 || state >= maxStates {
+					return fmt.Errorf("state of cell (%d, %d) must be between 0 and %d, got %d", x, y, maxStates-1, state)
+				}
+				states[y][x] = uint8(state)
+			}
+		}
+	}
+	universe, err := New(matrix, Options{
+		Colour:   u.Colour,
+		Rule:     rule,
+		Topology: topology,
+		Grid:     grid,
+		Engine:   u.Engine,
+		Step:     u.Step,
+		Palette:  u.Palette,
+		Colours:  colours,
+		States:   states,
+	})
+	if err != nil {
+		return err
+	}
+	universe.Soup = soup
+	*r = *universe
+	return nil
+}
@@ -0,0 +1,47 @@
+package universe
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodePatterns(t *testing.T) {
+	glider := parseCells(".....", "..o..", "...o.", ".ooo.", ".....")
+	tests := []struct {
+		name string
+		json string
+		rule string
+	}{
+		{"rle", `{"width": 5, "height": 5, "rle": "x = 3, y = 3, rule = B36/S23\nbo$2bo$3o!"}`, "B36/S23"},
+		{"detected rle", `{"width": 5, "height": 5, "pattern": "x = 3, y = 3\nbo$2bo$3o!"}`, "B3/S23"},
+		{"life106", `{"width": 5, "height": 5, "pattern": "#Life 1.06\n0 -1\n1 0\n-1 1\n0 1\n1 1\n"}`, "B3/S23"},
+		{"cells", `{"width": 5, "height": 5, "pattern": ".O\n..O\nOOO\n", "format": "cells"}`, "B3/S23"},
+		{"mc", `{"width": 5, "height": 5, "pattern": "[M2]\n#R highlife\n.*$..*$***$$$$$$\n"}`, "B36/S23"},
+	}
+	for _, tt := range tests {
+		var u Universe
+		if err := json.Unmarshal([]byte(tt.json), &u); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if !equalCells(u.Cells(), glider) {
+			t.Errorf("%s: cells %v, want a centred glider", tt.name, u.Cells())
+		}
+		if u.Rule.String() != tt.rule {
+			t.Errorf("%s: rule %s, want %s", tt.name, u.Rule, tt.rule)
+		}
+	}
+
+	for _, data := range []string{
+		`{"pattern": "x = 1, y = 1\no!", "rle": "x = 1, y = 1\no!"}`,
+		`{"pattern": "x = 1, y = 1\no!", "cells": [[true]]}`,
+		`{"pattern": "O\n", "format": "gif"}`,
+		`{"format": "cells", "cells": [[true]]}`,
+		`{"pattern": "O\n", "format": "rle"}`,
+	} {
+		var u Universe
+		if err := json.Unmarshal([]byte(data), &u); err == nil {
+			t.Errorf("%s decoded into a universe", data)
+		}
+	}
+}
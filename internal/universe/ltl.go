@@ -0,0 +1,183 @@
+package universe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CountRange is an inclusive range of weighted counts of alive neighbours
+type CountRange struct {
+	Min int
+	Max int
+}
+
+// CountRanges is a union of count ranges
+type CountRanges []CountRange
+
+// contains tells if any of the ranges contains the count
+func (r CountRanges) contains(count int) bool {
+	for _, cr := range r {
+		if count >= cr.Min && count <= cr.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns ranges as "34..58,60..62"
+func (r CountRanges) String() string {
+	parts := make([]string, len(r))
+	for i, cr := range r {
+		parts[i] = fmt.Sprintf("%d..%d", cr.Min, cr.Max)
+	}
+	return strings.Join(parts, ",")
+}
+
+// LargerThanLife is a rule counting alive cells of a neighbourhood of any
+// range, cells are born or survive when the count falls into given ranges
+type LargerThanLife struct {
+	Neighbourhood Neighbourhood
+	// Middle tells if the middle cell counts as its own neighbour.
+	Middle   bool
+	Birth    CountRanges
+	Survival CountRanges
+}
+
+// isLargerThanLife tells if a lower case rule is in "R5,C0,M1,S34..58,B34..45,NM" notation
+func isLargerThanLife(s string) bool {
+	return strings.HasPrefix(s, "r") && strings.Contains(s, ",")
+}
+
+// parseLargerThanLife parses a lower case rule in Larger than Life notation
+// "R<range>,C<states>,M<0|1>,S<min>..<max>,B<min>..<max>,N<M|N|H|W>", C, M &
+// N are optional. Counts may be listed HROT style as well ("S2-3,6,B3").
+// Rules of range 1 over the Moore neighbourhood end up in B/S tables.
+func parseLargerThanLife(s string, rule *Rule) error {
+	var (
+		radius                = -1
+		neighbours            = "m"
+		ltl                   LargerThanLife
+		ranges                *CountRanges
+		hasBirth, hasSurvival bool
+	)
+	for _, token := range strings.Split(s, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			return fmt.Errorf("empty item")
+		}
+		key, value := token[0], token[1:]
+		if key >= '0' && key <= '9' {
+			// More counts of the last birth or survival item.
+			if ranges == nil {
+				return fmt.Errorf("unexpected counts %q", token)
+			}
+			key, value = 0, token
+		}
+		switch key {
+		case 'r':
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxRange {
+				return fmt.Errorf("range must be between 1 and %d, got %q", maxRange, value)
+			}
+			radius = n
+		case 'c':
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > maxStates {
+				return fmt.Errorf("number of states must be between 0 and %d, got %q", maxStates, value)
+			}
+			if n > 2 {
+				rule.States = n
+			}
+		case 'm':
+			if value != "0" && value != "1" {
+				return fmt.Errorf("middle must be 0 or 1, got %q", value)
+			}
+			ltl.Middle = value == "1"
+		case 's', 'b':
+			if key == 'b' {
+				ranges, hasBirth = &ltl.Birth, true
+			} else {
+				ranges, hasSurvival = &ltl.Survival, true
+			}
+			if value == "" {
+				continue
+			}
+			fallthrough
+		case 0:
+			cr, err := parseCountRange(value)
+			if err != nil {
+				return err
+			}
+			*ranges = append(*ranges, cr)
+		case 'n':
+			neighbours = value
+		default:
+			return fmt.Errorf("unknown item %q", token)
+		}
+		if key != 's' && key != 'b' && key != 0 {
+			ranges = nil
+		}
+	}
+	if radius < 0 {
+		return fmt.Errorf("missing range R<1..%d>", maxRange)
+	}
+	if !hasBirth || !hasSurvival {
+		return fmt.Errorf("missing survival S<min>..<max> or birth B<min>..<max> counts")
+	}
+	var err error
+	if ltl.Neighbourhood, err = parseNeighbourhood(neighbours, radius); err != nil {
+		return err
+	}
+	maxCount := ltl.Neighbourhood.maxCount(ltl.Middle)
+	for _, cr := range append(append(CountRanges{}, ltl.Birth...), ltl.Survival...) {
+		if cr.Max > maxCount {
+			return fmt.Errorf("count %d exceeds %d neighbours", cr.Max, maxCount)
+		}
+	}
+
+	if radius == 1 && ltl.Neighbourhood.Kind == MooreNeighbourhood && !ltl.Middle {
+		// A plain outer-totalistic rule.
+		for n := 0; n <= maxNeighbours; n++ {
+			rule.Birth[n] = ltl.Birth.contains(n)
+			rule.Survival[n] = ltl.Survival.contains(n)
+		}
+		return nil
+	}
+	rule.Extended = &ltl
+	return nil
+}
+
+// parseCountRange parses "34..58", "2-3" or "6"
+func parseCountRange(value string) (CountRange, error) {
+	var cr CountRange
+	lower, upper, ok := strings.Cut(value, "..")
+	if !ok {
+		lower, upper, ok = strings.Cut(value, "-")
+	}
+	if !ok {
+		upper = lower
+	}
+	var errMin, errMax error
+	cr.Min, errMin = strconv.Atoi(lower)
+	cr.Max, errMax = strconv.Atoi(upper)
+	if errMin != nil || errMax != nil || cr.Min < 0 || cr.Min > cr.Max {
+		return cr, fmt.Errorf("invalid count range %q", value)
+	}
+	return cr, nil
+}
+
+// notation returns the rule with the given number of states in Larger than Life notation
+func (l LargerThanLife) notation(states int) string {
+	middle := 0
+	if l.Middle {
+		middle = 1
+	}
+	if states <= 2 {
+		states = 0
+	}
+	return fmt.Sprintf(
+		"R%d,C%d,M%d,S%s,B%s,%s",
+		l.Neighbourhood.Range, states, middle, l.Survival, l.Birth, l.Neighbourhood,
+	)
+}
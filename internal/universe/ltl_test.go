@@ -0,0 +1,142 @@
+package universe
+
+import "testing"
+
+func TestParseLargerThanLife(t *testing.T) {
+	tests := []struct {
+		notation string
+		want     string
+	}{
+		{"R5,C0,M1,S34..58,B34..45,NM", "R5,C0,M1,S34..58,B34..45,NM"},
+		{"r5,c0,m1,s34..58,b34..45,nm", "R5,C0,M1,S34..58,B34..45,NM"},
+		// C, M & N are optional.
+		{"R2,S5..8,B5..6", "R2,C0,M0,S5..8,B5..6,NM"},
+		// HROT style counts.
+		{"R2,C0,S2-3,6,B3,NN", "R2,C0,M0,S2..3,6..6,B3..3,NN"},
+		{"R3,C4,M0,S1..4,B2..3,NH", "R3,C4,M0,S1..4,B2..3,NH"},
+		{"R1,C0,M0,S1..2,B2..2,NW1a1000101", "R1,C0,M0,S1..2,B2..2,NW1a1000101"},
+		// Empty counts.
+		{"R2,C0,M0,S,B3..4,NM", "R2,C0,M0,S,B3..4,NM"},
+		// Range 1 over the Moore neighbourhood is a plain rule.
+		{"R1,C0,M0,S2..3,B3..3,NM", "B3/S23"},
+		{"R1,C3,M0,S2..3,B3..3,NM", "B3/S23/3"},
+		// Counting the middle cell needs the Larger than Life engine.
+		{"R1,C0,M1,S3..4,B3..3,NM", "R1,C0,M1,S3..4,B3..3,NM"},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.notation)
+		if err != nil {
+			t.Errorf("ParseRule(%q) failed: %s", tt.notation, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("ParseRule(%q) = %s, want %s", tt.notation, got, tt.want)
+		}
+		if again, err := ParseRule(rule.String()); err != nil || again.String() != tt.want {
+			t.Errorf("ParseRule(%q) = %s, %v, want the same rule", rule.String(), again, err)
+		}
+	}
+}
+
+func TestParseLargerThanLifeErrors(t *testing.T) {
+	for _, notation := range []string{
+		"C0,S2..3,B3..3",
+		"R0,S2..3,B3..3",
+		"R101,S2..3,B3..3",
+		"Rx,S2..3,B3..3",
+		"R2,C257,S2..3,B3..3",
+		"R2,M2,S2..3,B3..3",
+		"R2,S2..3",
+		"R2,B3..3",
+		"R2,S3..2,B3",
+		"R2,Sx,B3",
+		"R2,S2..3,B3..25,NM",
+		"R2,S2..3,B3..13,NN",
+		"R2,S2..3,B3,NX",
+		"R2,S2..3,B3,NMM",
+		"R1,S2..3,B3,NW1111",
+		"R1,S2..3,B3,NW11110111g",
+		"R2,,S2..3,B3",
+		"R2,X1,S2..3,B3",
+		"R2,4,S2..3,B3",
+	} {
+		if rule, err := ParseRule(notation); err == nil {
+			t.Errorf("ParseRule(%q) = %s, want an error", notation, rule)
+		}
+	}
+}
+
+func TestNeighbourhoodMaxCount(t *testing.T) {
+	tests := []struct {
+		notation string
+		middle   bool
+		want     int
+	}{
+		{"m", false, 8},
+		{"m", true, 9},
+		{"n", false, 4},
+		{"h", false, 6},
+		{"w1a1000101", false, 14},
+		{"w1a1000101", true, 14},
+	}
+	for _, tt := range tests {
+		n, err := parseNeighbourhood(tt.notation, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.maxCount(tt.middle); got != tt.want {
+			t.Errorf("%s middle %t: %d neighbours, want %d", n, tt.middle, got, tt.want)
+		}
+	}
+	for radius, want := range map[int]int{2: 24, 5: 120} {
+		n, _ := parseNeighbourhood("m", radius)
+		if got := n.maxCount(false); got != want {
+			t.Errorf("Moore neighbourhood of range %d: %d neighbours, want %d", radius, got, want)
+		}
+	}
+	if n, _ := parseNeighbourhood("n", 2); n.maxCount(false) != 12 {
+		t.Errorf("von Neumann neighbourhood of range 2: %d neighbours, want 12", n.maxCount(false))
+	}
+}
+
+func TestLargerThanLifeEvolution(t *testing.T) {
+	// Life counting the middle cell or over equally weighted neighbours is still Life.
+	for _, notation := range []string{"R1,C0,M1,S3..4,B3..3,NM", "R1,C0,M0,S2..3,B3..3,NW111101111"} {
+		for _, topology := range []Topology{Torus{}, Bounded{}, Klein{}} {
+			cells := randomCells(23, 17, 0.4, 3)
+			ltl, err := New(cells, Options{Rule: MustParseRule(notation), Topology: topology})
+			if err != nil {
+				t.Fatal(err)
+			}
+			life, err := New(cells, Options{Rule: ConwayRule, Topology: topology, Engine: "scalar"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for generation := 1; generation <= 20; generation++ {
+				ltl.Evolve()
+				life.Evolve()
+				if !equalCells(ltl.Cells(), life.Cells()) {
+					t.Fatalf("%s on %s differs from Life at generation %d", notation, topology.Name(), generation)
+				}
+			}
+		}
+	}
+
+	// A cell with 4 von Neumann neighbours of range 1 is born, diagonal ones don't count.
+	got := evolved(t, parseCells(".o.o.", "o...o", ".....", "o...o", ".o.o."), Options{
+		Rule:     MustParseRule("R1,C0,M0,S,B4,NN"),
+		Topology: Bounded{},
+	}, 1)
+	want := parseCells(".....", ".....", ".....", ".....", ".....")
+	if !equalCells(got, want) {
+		t.Errorf("diagonal neighbours gave birth: %v", got)
+	}
+	got = evolved(t, parseCells("..o..", ".....", "o...o", ".....", "..o.."), Options{
+		Rule:     MustParseRule("R2,C0,M0,S,B4,NN"),
+		Topology: Bounded{},
+	}, 1)
+	want = parseCells(".....", ".....", "..o..", ".....", ".....")
+	if !equalCells(got, want) {
+		t.Errorf("von Neumann neighbours of range 2 evolved into %v, want %v", got, want)
+	}
+}
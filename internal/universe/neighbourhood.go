@@ -0,0 +1,242 @@
+package universe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRange is the largest range of a neighbourhood.
+const maxRange = 100
+
+// Neighbourhood kinds in Larger than Life notation.
+const (
+	MooreNeighbourhood      = 'M'
+	VonNeumannNeighbourhood = 'N'
+	HexagonalNeighbourhood  = 'H'
+	WeightedNeighbourhood   = 'W'
+)
+
+// Neighbourhood describes cells around a cell which count as its neighbours
+type Neighbourhood struct {
+	// Kind is MooreNeighbourhood, VonNeumannNeighbourhood, HexagonalNeighbourhood or WeightedNeighbourhood.
+	Kind byte
+	// Range is the largest distance of a neighbour on either axis.
+	Range int
+	// Weights of a weighted neighbourhood, 2*Range+1 rows of 2*Range+1 cells around the middle one.
+	Weights [][]int
+}
+
+// parseNeighbourhood parses a neighbourhood of the given range, "m", "n", "h" or "w" followed by hex weights
+func parseNeighbourhood(value string, radius int) (Neighbourhood, error) {
+	n := Neighbourhood{Range: radius}
+	if value == "" {
+		return n, fmt.Errorf("empty neighbourhood")
+	}
+	n.Kind = strings.ToUpper(value[:1])[0]
+	switch n.Kind {
+	case MooreNeighbourhood, VonNeumannNeighbourhood, HexagonalNeighbourhood:
+		if len(value) > 1 {
+			return n, fmt.Errorf("unexpected %q after neighbourhood %q", value[1:], value[:1])
+		}
+	case WeightedNeighbourhood:
+		size := 2*radius + 1
+		digits := value[1:]
+		if len(digits) != size*size {
+			return n, fmt.Errorf("weighted neighbourhood of range %d needs %d hex weights, got %d", radius, size*size, len(digits))
+		}
+		n.Weights = make([][]int, size)
+		for y := range n.Weights {
+			n.Weights[y] = make([]int, size)
+			for x := range n.Weights[y] {
+				d := digits[y*size+x]
+				switch {
+				case d >= '0' && d <= '9':
+					n.Weights[y][x] = int(d - '0')
+				case d >= 'a' && d <= 'f':
+					n.Weights[y][x] = int(d-'a') + 10
+				default:
+					return n, fmt.Errorf("invalid weight %q", d)
+				}
+			}
+		}
+	default:
+		return n, fmt.Errorf("unknown neighbourhood %q, expected M, N, H or W", value[:1])
+	}
+	return n, nil
+}
+
+// String returns the neighbourhood in Larger than Life notation, "NM" or "NW" followed by hex weights
+func (n Neighbourhood) String() string {
+	var sb strings.Builder
+	sb.WriteByte('N')
+	sb.WriteByte(n.Kind)
+	for _, row := range n.Weights {
+		for _, weight := range row {
+			fmt.Fprintf(&sb, "%x", weight)
+		}
+	}
+	return sb.String()
+}
+
+// weights returns the weight of each cell of the neighbourhood, the middle one
+// counts only if middle is set
+func (n Neighbourhood) weights(middle bool) [][]int {
+	size := 2*n.Range + 1
+	weights := make([][]int, size)
+	for y := range weights {
+		weights[y] = make([]int, size)
+		dy := y - n.Range
+		for x := range weights[y] {
+			dx := x - n.Range
+			switch n.Kind {
+			case MooreNeighbourhood:
+				weights[y][x] = 1
+			case VonNeumannNeighbourhood:
+				if abs(dx)+abs(dy) <= n.Range {
+					weights[y][x] = 1
+				}
+			case HexagonalNeighbourhood:
+				// Hexagons drawn on a square grid lean to the right, top right &
+				// bottom left corners are cut off.
+				if abs(dx-dy) <= n.Range {
+					weights[y][x] = 1
+				}
+			case WeightedNeighbourhood:
+				weights[y][x] = n.Weights[y][x]
+			}
+		}
+	}
+	if !middle {
+		weights[n.Range][n.Range] = 0
+	}
+	return weights
+}
+
+// maxCount returns the largest possible weighted count of alive neighbours
+func (n Neighbourhood) maxCount(middle bool) int {
+	var count int
+	for _, row := range n.weights(middle) {
+		for _, weight := range row {
+			count += weight
+		}
+	}
+	return count
+}
+
+// weightedRect is a rectangle of neighbours with the same weight, offsets
+// relative to the middle cell are inclusive
+type weightedRect struct {
+	x0, y0, x1, y1 int
+	weight         int
+}
+
+// rects covers non-zero weights with as few rectangles as rows of equal
+// weight runs allow, equal runs of adjacent rows are joined
+func rects(weights [][]int) []weightedRect {
+	radius := len(weights) / 2
+	var done, open []weightedRect
+	for y, row := range weights {
+		var runs []weightedRect
+		for x := 0; x < len(row); {
+			end := x
+			for end+1 < len(row) && row[end+1] == row[x] {
+				end++
+			}
+			if row[x] != 0 {
+				runs = append(runs, weightedRect{x - radius, y - radius, end - radius, y - radius, row[x]})
+			}
+			x = end + 1
+		}
+		var next []weightedRect
+		for _, run := range runs {
+			for i, r := range open {
+				if r.x0 == run.x0 && r.x1 == run.x1 && r.weight == run.weight {
+					run.y0 = r.y0
+					open = append(open[:i], open[i+1:]...)
+					break
+				}
+			}
+			next = append(next, run)
+		}
+		done = append(done, open...)
+		open = next
+	}
+	return append(done, open...)
+}
+
+// summedAreaTable counts weighted alive neighbours of any range in constant
+// time per rectangle of the neighbourhood
+// Cells are padded by the range on each side, padding cells are resolved
+// through the topology once.
+type summedAreaTable struct {
+	width  int
+	height int
+	radius int
+	rects  []weightedRect
+	// sources holds the index of the cell each padded cell mirrors, -1 for dead ones.
+	sources []int
+	// sums holds counts of alive cells above & left of each padded cell, with an extra zero row & column.
+	sums []int32
+}
+
+// newSummedAreaTable creates a summedAreaTable for a neighbourhood of width x height cells
+func newSummedAreaTable(n Neighbourhood, middle bool, width, height int, topology Topology) *summedAreaTable {
+	t := &summedAreaTable{
+		width:  width,
+		height: height,
+		radius: n.Range,
+		rects:  rects(n.weights(middle)),
+	}
+	paddedWidth, paddedHeight := width+2*n.Range, height+2*n.Range
+	t.sources = make([]int, paddedWidth*paddedHeight)
+	for py := 0; py < paddedHeight; py++ {
+		for px := 0; px < paddedWidth; px++ {
+			x, y, ok := topology.Resolve(px-n.Range, py-n.Range, width, height)
+			if !ok {
+				t.sources[py*paddedWidth+px] = -1
+				continue
+			}
+			t.sources[py*paddedWidth+px] = y*width + x
+		}
+	}
+	t.sums = make([]int32, (paddedWidth+1)*(paddedHeight+1))
+	return t
+}
+
+// update recomputes sums of cells in the given state
+func (t *summedAreaTable) update(cells []uint8, state uint8) {
+	paddedWidth, paddedHeight := t.width+2*t.radius, t.height+2*t.radius
+	stride := paddedWidth + 1
+	for py := 0; py < paddedHeight; py++ {
+		var rowSum int32
+		for px := 0; px < paddedWidth; px++ {
+			if source := t.sources[py*paddedWidth+px]; source >= 0 && cells[source] == state {
+				rowSum++
+			}
+			t.sums[(py+1)*stride+px+1] = t.sums[py*stride+px+1] + rowSum
+		}
+	}
+}
+
+// count returns the weighted count of neighbours of the cell at (x, y)
+func (t *summedAreaTable) count(x, y int) int {
+	stride := t.width + 2*t.radius + 1
+	// Padded coordinates of the cell.
+	x, y = x+t.radius, y+t.radius
+	var count int32
+	for _, r := range t.rects {
+		left, right := x+r.x0, x+r.x1+1
+		top, bottom := y+r.y0, y+r.y1+1
+		sum := t.sums[bottom*stride+right] - t.sums[top*stride+right] - t.sums[bottom*stride+left] + t.sums[top*stride+left]
+		count += sum * int32(r.weight)
+	}
+	return int(count)
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,68 @@
+package universe
+
+// Patch holds changes to a Universe, nil fields are left untouched
+type Patch struct {
+	Colour   *string `json:"colour"`
+	Rule     *Rule   `json:"rule"`
+	Topology *string `json:"topology"`
+	Engine   *string `json:"engine"`
+	Step     *uint   `json:"step"`
+}
+
+// Apply changes the Universe, nothing is changed on error
+// Alive cells & the generation number are kept, a change of the rule, topology,
+// engine or step restarts period detection & the history, only cells within
+// the window of an unbounded plane survive it. Switching the engine without a topology picks
+// the new engine's default one.
+func (r *Universe) Apply(p Patch) error {
+	if p.Rule == nil && p.Topology == nil && p.Engine == nil && p.Step == nil {
+		if p.Colour != nil {
+			r.Colour = *p.Colour
+		}
+		return nil
+	}
+
+	opts := r.options()
+	if p.Rule != nil && p.Rule.Species > 0 && p.Rule.Species != r.Rule.Species {
+		// Colours of another number of species don't map, all cells take the first one.
+		opts.Palette, opts.Colours = nil, nil
+	}
+	if p.Colour != nil {
+		opts.Colour = *p.Colour
+	}
+	if p.Rule != nil {
+		opts.Rule = *p.Rule
+		if p.Engine == nil && r.Engine == defaultEngineFor(r.Rule, r.Grid) {
+			// The default engine of the new rule takes over.
+			opts.Engine = ""
+		}
+	}
+	if p.Engine != nil {
+		opts.Engine = *p.Engine
+		opts.Topology = nil
+	}
+	if p.Topology != nil {
+		topology, err := ParseTopology(*p.Topology)
+		if err != nil {
+			return err
+		}
+		opts.Topology = topology
+	}
+	if p.Step != nil {
+		opts.Step = *p.Step
+	}
+
+	u, err := New(r.Cells(), opts)
+	if err != nil {
+		return err
+	}
+	u.ID = r.ID
+	u.Soup = r.Soup
+	u.Rewinds = r.Rewinds
+	u.generationNumber = r.generationNumber
+	if r.history != nil {
+		u.SetHistoryDepth(r.history.depth)
+	}
+	*r = *u
+	return nil
+}
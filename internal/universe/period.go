@@ -0,0 +1,168 @@
+package universe
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// DefaultMaxPeriod is the longest period detected when none is given.
+	DefaultMaxPeriod = 30
+	// maxShapePopulation limits the population whose shape is tracked,
+	// spaceships are small and hashing huge patterns every tick is expensive.
+	maxShapePopulation = 1 << 14
+)
+
+// Velocity is a displacement of a spaceship per period
+type Velocity struct {
+	DX int `json:"dx"`
+	DY int `json:"dy"`
+}
+
+// wrap maps a displacement on a torus of the size to the shortest one
+// A spaceship crossing the seam moves its bounding box by almost a whole size.
+func (v Velocity) wrap(width, height int) Velocity {
+	return Velocity{
+		DX: floorMod(v.DX+width/2, width) - width/2,
+		DY: floorMod(v.DY+height/2, height) - height/2,
+	}
+}
+
+// IsZero tells if there's no displacement
+func (v Velocity) IsZero() bool {
+	return v.DX == 0 && v.DY == 0
+}
+
+// Format returns the velocity in "(dx,dy)c/p" notation
+func (v Velocity) Format(period int) string {
+	return fmt.Sprintf("(%d,%d)c/%d", v.DX, v.DY, period)
+}
+
+// signature describes a generation for period detection
+type signature struct {
+	generation int
+	// hash is a position dependent hash of cells.
+	hash uint64
+	// shape is a translation invariant hash of alive cells, 0 if unknown.
+	shape uint64
+	// x & y are the top left corner of alive cells bounding box.
+	x int
+	y int
+}
+
+// periodMatch describes a previous generation the current one repeats
+type periodMatch struct {
+	generation int
+	velocity   Velocity
+}
+
+// periodDetector remembers signatures of recent generations to find periodic behaviour
+type periodDetector struct {
+	signatures []signature
+	// next is the ring buffer position of the next observation.
+	next  int
+	count int
+}
+
+// newPeriodDetector creates a detector remembering up to maxPeriod observations
+func newPeriodDetector(maxPeriod int) *periodDetector {
+	return &periodDetector{signatures: make([]signature, maxPeriod)}
+}
+
+// observe records a signature and looks for a previous generation with the same
+// cells, at the same position (oscillator) or shifted (spaceship).
+func (d *periodDetector) observe(s signature) (periodMatch, bool) {
+	size := len(d.signatures)
+	if size == 0 {
+		return periodMatch{}, false
+	}
+	var match periodMatch
+	found := false
+	// Walk back from the most recent observation, so the shortest period wins.
+	for i := 1; i <= d.count; i++ {
+		prev := d.signatures[(d.next-i+size)%size]
+		if prev.hash == s.hash {
+			match, found = periodMatch{generation: prev.generation}, true
+			break
+		}
+		if s.shape != 0 && prev.shape == s.shape {
+			match, found = periodMatch{
+				generation: prev.generation,
+				velocity:   Velocity{DX: s.x - prev.x, DY: s.y - prev.y},
+			}, true
+			break
+		}
+	}
+	d.signatures[d.next] = s
+	d.next = (d.next + 1) % size
+	if d.count < size {
+		d.count++
+	}
+	return match, found
+}
+
+// shapeOf calculates a translation invariant hash of alive cells and
+// the top left corner of their bounding box. Cells may come in any order.
+func shapeOf(cells [][2]int) (hash uint64, x, y int) {
+	if len(cells) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i][1] != cells[j][1] {
+			return cells[i][1] < cells[j][1]
+		}
+		return cells[i][0] < cells[j][0]
+	})
+	x, y = cells[0][0], cells[0][1]
+	for _, c := range cells {
+		if c[0] < x {
+			x = c[0]
+		}
+	}
+	hash = mixHash(uint64(len(cells)))
+	for _, c := range cells {
+		hash = mixHash(hash, uint64(c[0]-x), uint64(c[1]-y))
+	}
+	// 0 is reserved for an unknown shape.
+	if hash == 0 {
+		hash = 1
+	}
+	return hash, x, y
+}
+
+// unwrapTorus shifts cells across the seam of a torus of the size, so their
+// bounding box starts after the longest empty runs of columns & rows and
+// a pattern straddling the seam keeps its shape
+func unwrapTorus(cells [][2]int, width, height int) {
+	columns, rows := make([]bool, width), make([]bool, height)
+	for _, c := range cells {
+		columns[c[0]], rows[c[1]] = true, true
+	}
+	x, y := seamStart(columns), seamStart(rows)
+	for i, c := range cells {
+		if c[0] < x {
+			cells[i][0] += width
+		}
+		if c[1] < y {
+			cells[i][1] += height
+		}
+	}
+}
+
+// seamStart returns the position following the longest cyclic run of
+// unoccupied ones, the first of equally long runs wins
+func seamStart(occupied []bool) int {
+	n := len(occupied)
+	longest, end, run := 0, -1, 0
+	// Twice around, so a run may wrap.
+	for i := 0; i < 2*n; i++ {
+		if occupied[i%n] {
+			run = 0
+			continue
+		}
+		if run++; run > longest && run <= n {
+			longest, end = run, i
+		}
+	}
+	return (end + 1) % n
+}
@@ -0,0 +1,139 @@
+package universe
+
+import "testing"
+
+// settle evolves the universe until it settles, at most for the number of generations
+func settle(u *Universe, generations int) {
+	for i := 0; i < generations && !u.IsSettled(); i++ {
+		u.Evolve()
+	}
+}
+
+func TestDetectOscillators(t *testing.T) {
+	tests := []struct {
+		name   string
+		cells  [][]bool
+		period int
+		static bool
+	}{
+		{"block", parseCells("....", ".oo.", ".oo.", "...."), 1, true},
+		{"blinker", parseCells(".....", "..o..", "..o..", "..o..", "....."), 2, false},
+		{"toad", parseCells("......", "......", "..ooo.", ".ooo..", "......", "......"), 2, false},
+		{"empty", parseCells("...", ".o.", "..."), 1, true},
+	}
+	for _, tt := range tests {
+		for _, topology := range []Topology{Bounded{}, Torus{}} {
+			u, err := New(tt.cells, Options{Rule: ConwayRule, Topology: topology})
+			if err != nil {
+				t.Fatal(err)
+			}
+			settle(u, 20)
+			if u.Period != tt.period || u.IsStatic != tt.static {
+				t.Errorf(
+					"%s on %s: period %d static %t, want period %d static %t",
+					tt.name, topology.Name(), u.Period, u.IsStatic, tt.period, tt.static,
+				)
+			}
+		}
+	}
+}
+
+func TestDetectSpaceships(t *testing.T) {
+	glider := parseCells(".o......", "..o.....", "ooo.....", "........", "........", "........", "........", "........")
+	for name, topology := range topologies {
+		engine := ""
+		if _, ok := topology.(Unbounded); ok {
+			engine = "hashlife"
+		}
+		u, err := New(glider, Options{Rule: ConwayRule, Topology: topology, Engine: engine})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Generations are looked at before they evolve, the 4th one on the 5th tick.
+		settle(u, 5)
+		if !isTranslationInvariant(topology) {
+			// The glider hits an edge, it isn't a spaceship there.
+			if u.IsSettled() {
+				t.Errorf("%s: glider settled as %q", name, u.Convergence())
+			}
+			continue
+		}
+		if u.Period != 4 || u.Velocity != (Velocity{DX: 1, DY: 1}) {
+			t.Errorf("%s: glider settled as %q, want a (1,1)c/4 spaceship", name, u.Convergence())
+		}
+	}
+
+	// On a bounded plane the glider turns into a block in the corner & settles then.
+	u, err := New(glider, Options{Rule: ConwayRule, Topology: Bounded{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settle(u, 100)
+	if !u.IsStatic || u.Stats().Alive != 4 {
+		t.Errorf("glider on a bounded plane settled as %q with %d cells", u.Convergence(), u.Stats().Alive)
+	}
+}
+
+func TestDetectSpaceshipsAcrossSeam(t *testing.T) {
+	// A glider at every position of a torus, straddling its seams at some of them.
+	glider := [][2]int{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}
+	const size = 8
+	immigration := MustParseRule("immigration")
+	tests := []struct {
+		name    string
+		opts    Options
+		colours bool
+	}{
+		{"bitwise", Options{Rule: ConwayRule, Topology: Torus{}}, false},
+		{"multistate", Options{Rule: ConwayRule, Topology: Torus{}, Engine: "multistate"}, false},
+		{"multi-colour", Options{Rule: immigration, Topology: Torus{}}, true},
+	}
+	for _, tt := range tests {
+		for offset := 0; offset < size*size; offset++ {
+			cells := make([][]bool, size)
+			colours := make([][]uint8, size)
+			for y := range cells {
+				cells[y] = make([]bool, size)
+				colours[y] = make([]uint8, size)
+			}
+			for _, c := range glider {
+				x, y := (c[0]+offset%size)%size, (c[1]+offset/size)%size
+				cells[y][x] = true
+				colours[y][x] = 1
+			}
+			opts := tt.opts
+			if tt.colours {
+				opts.Colours = colours
+			}
+			u, err := New(cells, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			settle(u, 5)
+			if u.Period != 4 || u.Velocity != (Velocity{DX: 1, DY: 1}) {
+				t.Errorf("%s: glider at (%d, %d) settled as %q, want a (1,1)c/4 spaceship",
+					tt.name, offset%size, offset/size, u.Convergence())
+			}
+		}
+	}
+}
+
+func TestColoursArePartOfShape(t *testing.T) {
+	cells := parseCells("....", ".oo.", ".oo.", "....")
+	shapes := map[uint64]bool{}
+	for _, colours := range [][][]uint8{
+		{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}},
+		{{0, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}},
+	} {
+		plane, err := newColourPlane(speciesPalette[:2], colours, cells, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alive := [][2]int{{1, 1}, {2, 1}, {1, 2}, {2, 2}}
+		shape, _, _ := shapeOf(alive)
+		shapes[plane.shape(shape, alive)] = true
+	}
+	if len(shapes) != 2 {
+		t.Errorf("blocks of different colours have the same shape")
+	}
+}
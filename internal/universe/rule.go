@@ -0,0 +1,247 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxNeighbours is the number of neighbours in a Moore neighbourhood.
+	maxNeighbours = 8
+	// maxStates is the largest number of cell states of a Generations rule.
+	maxStates = 256
+)
+
+// ConwayRule is the classic Game of Life rule, used when no rule is given.
+var ConwayRule = MustParseRule("B3/S23")
+
+// namedRules maps well known rule names to their B/S notation.
+var namedRules = map[string]string{
+	"life":        "B3/S23",
+	"conway":      "B3/S23",
+	"highlife":    "B36/S23",
+	"seeds":       "B2/S",
+	"daynight":    "B3678/S34678",
+	"life34":      "B34/S34",
+	"replicator":  "B1357/S1357",
+	"maze":        "B3/S12345",
+	"2x2":         "B36/S125",
+	"morley":      "B368/S245",
+	"briansbrain": "B2/S/3",
+	"starwars":    "B2/S345/4",
+}
+
+// speciesNames maps numbers of species of multi-colour rules to their names.
+var speciesNames = map[int]string{
+	2: "immigration",
+	4: "quadlife",
+}
+
+// Rule represents an outer-totalistic birth/survival rule in B/S notation
+// Birth[n] tells if a dead cell with n alive neighbours becomes alive,
+// Survival[n] tells if an alive cell with n alive neighbours stays alive.
+type Rule struct {
+	Birth    [maxNeighbours + 1]bool
+	Survival [maxNeighbours + 1]bool
+	// Species is the number of colours alive cells of a multi-colour rule
+	// (Immigration, QuadLife) carry, 0 for single colour rules.
+	Species int
+	// States is the number of cell states of a Generations rule, alive cells
+	// failing to survive pass through States-2 dying states before they're
+	// dead. 0 for rules with alive & dead cells only.
+	States int
+	// Extended is a Larger than Life rule over a custom neighbourhood, Birth &
+	// Survival are unused then. nil for rules over the 8 Moore neighbours.
+	Extended *LargerThanLife
+	// Isotropic tells which configurations of the 8 neighbours give birth or
+	// survive, nil for outer-totalistic rules.
+	Isotropic *Configurations
+}
+
+// IsGenerations tells if cells have dying states
+func (r Rule) IsGenerations() bool {
+	return r.States > 2
+}
+
+// isTwoStateMoore tells if the rule has alive & dead cells only and looks at the 8 Moore neighbours
+func (r Rule) isTwoStateMoore() bool {
+	return !r.IsGenerations() && r.Extended == nil
+}
+
+// ParseRule parses a rule in "B36/S23" notation.
+// The legacy "S/B" notation ("23/36") and well known names ("highlife") are accepted as well.
+// Multi-colour variants are named "immigration" & "quadlife", or follow the notation ("B36/S23 quadlife").
+// Generations rules add the number of states ("B2/S/3", "345/2/4").
+// Larger than Life rules count neighbours of any range ("R5,C0,M1,S34..58,B34..45,NM").
+// Isotropic non-totalistic rules follow counts with letters of configurations in Hensel notation ("B2-a/S12").
+func ParseRule(notation string) (Rule, error) {
+	var rule Rule
+	s := strings.ToLower(strings.TrimSpace(notation))
+	if s == "" {
+		return rule, fmt.Errorf("empty rule")
+	}
+	if fields := strings.Fields(s); len(fields) > 0 {
+		for species, name := range speciesNames {
+			if fields[len(fields)-1] == name {
+				rule.Species = species
+				s = strings.Join(fields[:len(fields)-1], " ")
+			}
+		}
+		if s == "" {
+			// Named multi-colour rules are variants of Conway's one.
+			s = "b3/s23"
+		}
+	}
+	if named, ok := namedRules[strings.NewReplacer(" ", "", "&", "", "'", "").Replace(s)]; ok {
+		s = strings.ToLower(named)
+	}
+
+	if isLargerThanLife(s) {
+		if rule.Species > 0 {
+			return rule, fmt.Errorf("rule %q: multi-colour rules count Moore neighbours only", notation)
+		}
+		if err := parseLargerThanLife(s, &rule); err != nil {
+			return rule, fmt.Errorf("rule %q: %w", notation, err)
+		}
+		return rule, nil
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) == 3 {
+		if err := parseStates(parts[2], &rule); err != nil {
+			return rule, fmt.Errorf("rule %q: %w", notation, err)
+		}
+		parts = parts[:2]
+		s = parts[0] + "/" + parts[1]
+	}
+	if len(parts) != 2 {
+		return rule, fmt.Errorf("rule %q: expected B<digits>/S<digits> or B<digits>/S<digits>/<states>", notation)
+	}
+
+	var birth, survival string
+	switch {
+	case strings.HasPrefix(parts[0], "b") && strings.HasPrefix(parts[1], "s"):
+		birth, survival = parts[0][1:], parts[1][1:]
+	case strings.HasPrefix(parts[0], "s") && strings.HasPrefix(parts[1], "b"):
+		survival, birth = parts[0][1:], parts[1][1:]
+	case !strings.ContainsAny(s, "bs"):
+		// Legacy notation lists survival counts first.
+		survival, birth = parts[0], parts[1]
+	default:
+		return rule, fmt.Errorf("rule %q: expected B<digits>/S<digits>", notation)
+	}
+
+	if isHensel(birth, survival) {
+		if err := parseHensel(birth, survival, &rule); err != nil {
+			return rule, fmt.Errorf("rule %q: %w", notation, err)
+		}
+		return rule, nil
+	}
+	if err := parseCounts(birth, &rule.Birth); err != nil {
+		return rule, fmt.Errorf("rule %q: birth: %w", notation, err)
+	}
+	if err := parseCounts(survival, &rule.Survival); err != nil {
+		return rule, fmt.Errorf("rule %q: survival: %w", notation, err)
+	}
+	return rule, nil
+}
+
+// parseStates parses the number of states of a Generations rule, "3" or "c3"
+func parseStates(value string, rule *Rule) error {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "c"), "g")
+	states, err := strconv.Atoi(value)
+	if err != nil || states < 2 || states > maxStates {
+		return fmt.Errorf("number of states must be between 2 and %d, got %q", maxStates, value)
+	}
+	if rule.Species > 0 {
+		return fmt.Errorf("multi-colour rules can't have dying states")
+	}
+	if states > 2 {
+		rule.States = states
+	}
+	return nil
+}
+
+// MustParseRule is like ParseRule but panics if the notation is invalid
+func MustParseRule(notation string) Rule {
+	rule, err := ParseRule(notation)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+// parseCounts fills a neighbours count table from a string of digits
+func parseCounts(digits string, table *[maxNeighbours + 1]bool) error {
+	for _, d := range digits {
+		if d < '0' || d > '0'+maxNeighbours {
+			return fmt.Errorf("invalid neighbours count %q", d)
+		}
+		if table[d-'0'] {
+			return fmt.Errorf("duplicated neighbours count %q", d)
+		}
+		table[d-'0'] = true
+	}
+	return nil
+}
+
+// String returns the canonical B/S notation of the Rule
+func (r Rule) String() string {
+	if r.Species > 0 {
+		base := r
+		base.Species = 0
+		if base == ConwayRule {
+			return speciesNames[r.Species]
+		}
+		return base.String() + " " + speciesNames[r.Species]
+	}
+	if r.Extended != nil {
+		return r.Extended.notation(r.States)
+	}
+	var sb strings.Builder
+	sb.WriteString("B")
+	if r.Isotropic != nil {
+		sb.WriteString(henselCounts(&r.Isotropic.Birth))
+	} else {
+		for n, ok := range r.Birth {
+			if ok {
+				sb.WriteByte(byte('0' + n))
+			}
+		}
+	}
+	sb.WriteString("/S")
+	if r.Isotropic != nil {
+		sb.WriteString(henselCounts(&r.Isotropic.Survival))
+	} else {
+		for n, ok := range r.Survival {
+			if ok {
+				sb.WriteByte(byte('0' + n))
+			}
+		}
+	}
+	if r.IsGenerations() {
+		fmt.Fprintf(&sb, "/%d", r.States)
+	}
+	return sb.String()
+}
+
+// MarshalJSON encodes the Rule as a B/S notation string
+func (r Rule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes and validates a Rule from a B/S notation string
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var notation string
+	if err := json.Unmarshal(data, &notation); err != nil {
+		return fmt.Errorf("rule must be a string: %w", err)
+	}
+	rule, err := ParseRule(notation)
+	if err != nil {
+		return err
+	}
+	*r = rule
+	return nil
+}
@@ -0,0 +1,195 @@
+package universe
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		notation string
+		want     string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b36/s23", "B36/S23"},
+		{" B3/S23 ", "B3/S23"},
+		{"S23/B3", "B3/S23"},
+		{"23/3", "B3/S23"},
+		{"/2", "B2/S"},
+		{"B2/S", "B2/S"},
+		{"B/S012345678", "B/S012345678"},
+		{"highlife", "B36/S23"},
+		{"Day & Night", "B3678/S34678"},
+		{"B2/S/3", "B2/S/3"},
+		{"B2/S/C3", "B2/S/3"},
+		{"345/2/4", "B2/S345/4"},
+		{"briansbrain", "B2/S/3"},
+		{"B3/S23/2", "B3/S23"},
+		{"immigration", "immigration"},
+		{"B36/S23 quadlife", "B36/S23 quadlife"},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.notation)
+		if err != nil {
+			t.Errorf("ParseRule(%q) failed: %s", tt.notation, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("ParseRule(%q) = %s, want %s", tt.notation, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleTables(t *testing.T) {
+	rule := MustParseRule("B36/S23")
+	for n := 0; n <= maxNeighbours; n++ {
+		if rule.Birth[n] != (n == 3 || n == 6) {
+			t.Errorf("Birth[%d] = %t", n, rule.Birth[n])
+		}
+		if rule.Survival[n] != (n == 2 || n == 3) {
+			t.Errorf("Survival[%d] = %t", n, rule.Survival[n])
+		}
+	}
+	generations := MustParseRule("B2/S/3")
+	if !generations.IsGenerations() || generations.States != 3 {
+		t.Errorf("B2/S/3 has %d states", generations.States)
+	}
+	if MustParseRule("B3/S23").IsGenerations() {
+		t.Errorf("B3/S23 is a Generations rule")
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, notation := range []string{
+		"",
+		"B3",
+		"B9/S23",
+		"B33/S23",
+		"B3/S2x",
+		"X3/Y23",
+		"B3/S23/1",
+		"B3/S23/257",
+		"B3/S23/x",
+		"B2/S/3 immigration",
+	} {
+		if rule, err := ParseRule(notation); err == nil {
+			t.Errorf("ParseRule(%q) = %s, want an error", notation, rule)
+		}
+	}
+}
+
+func TestRuleJSON(t *testing.T) {
+	var rule Rule
+	if err := json.Unmarshal([]byte(`"23/36"`), &rule); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"B36/S23"` {
+		t.Errorf("got %s, want \"B36/S23\"", data)
+	}
+	if err = json.Unmarshal([]byte(`3`), &rule); err == nil {
+		t.Errorf("a number decoded into a rule")
+	}
+}
+
+// parseCells parses rows of cells, "o" is alive
+func parseCells(rows ...string) [][]bool {
+	cells := make([][]bool, len(rows))
+	for y, row := range rows {
+		cells[y] = make([]bool, len(row))
+		for x, c := range row {
+			cells[y][x] = c == 'o'
+		}
+	}
+	return cells
+}
+
+// evolved evolves cells by the rule for the number of generations
+func evolved(t *testing.T, cells [][]bool, opts Options, generations int) [][]bool {
+	t.Helper()
+	u, err := New(cells, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < generations; i++ {
+		u.Evolve()
+	}
+	return u.Cells()
+}
+
+// equalCells tells if both matrices hold the same cells
+func equalCells(a, b [][]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for y := range a {
+		if len(a[y]) != len(b[y]) {
+			return false
+		}
+		for x := range a[y] {
+			if a[y][x] != b[y][x] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestRuleEvolution(t *testing.T) {
+	tests := []struct {
+		rule  string
+		cells [][]bool
+		want  [][]bool
+	}{
+		// Blinker.
+		{
+			"B3/S23",
+			parseCells(".....", "..o..", "..o..", "..o..", "....."),
+			parseCells(".....", ".....", ".ooo.", ".....", "....."),
+		},
+		// Seeds: every alive cell dies, pairs give birth.
+		{"B2/S", parseCells("....", ".oo.", "....", "...."), parseCells(".oo.", "....", ".oo.", "....")},
+		// Everything survives, nothing is born.
+		{"B/S012345678", parseCells("....", ".o..", "..o.", "...."), parseCells("....", ".o..", "..o.", "....")},
+	}
+	for _, tt := range tests {
+		got := evolved(t, tt.cells, Options{Rule: MustParseRule(tt.rule), Topology: Bounded{}}, 1)
+		if !equalCells(got, tt.want) {
+			t.Errorf("%s evolved into %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestGenerationsStates(t *testing.T) {
+	u, err := New(parseCells(".....", ".....", ".oo..", ".....", "....."), Options{
+		Rule:     MustParseRule("B2/S/3"),
+		Topology: Bounded{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Evolve()
+	// Alive cells start dying, cells with 2 alive neighbours are born.
+	want := [][]uint8{
+		{0, 0, 0, 0, 0},
+		{0, 1, 1, 0, 0},
+		{0, 2, 2, 0, 0},
+		{0, 1, 1, 0, 0},
+		{0, 0, 0, 0, 0},
+	}
+	got := u.States()
+	for y := range want {
+		for x := range want[y] {
+			if got[y][x] != want[y][x] {
+				t.Fatalf("states %v, want %v", got, want)
+			}
+		}
+	}
+	u.Evolve()
+	if states := u.States(); states[2][1] != 0 || states[2][2] != 0 {
+		t.Errorf("dying cells didn't die: %v", states)
+	}
+}
@@ -0,0 +1,223 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ride90/game-of-life/internal/pattern"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDensity is the share of alive cells of a soup when none is given.
+	DefaultDensity = 0.5
+	// DefaultSymmetry is used when a soup has no symmetry given.
+	DefaultSymmetry = "C1"
+)
+
+// Soup describes a random universe, the same soup always gives the same cells
+type Soup struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Density is the probability of a cell being alive, DefaultDensity if 0.
+	Density float64 `json:"density"`
+	// Symmetry is the name of a symmetry group of the soup, DefaultSymmetry if empty.
+	Symmetry string `json:"symmetry"`
+	// Seed of the random source, a random one is picked if nil.
+	Seed *Seed `json:"seed"`
+}
+
+// Seed is a 64-bit seed of a soup
+// It's encoded as a decimal string, JavaScript numbers keep 53 bits only,
+// numbers are decoded too.
+type Seed uint64
+
+// MarshalJSON encodes the seed as a decimal string
+func (s Seed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(s), 10))
+}
+
+// UnmarshalJSON decodes the seed from a decimal string or a number
+func (s *Seed) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	seed, err := strconv.ParseUint(strings.TrimSpace(text), 10, 64)
+	if err != nil {
+		return fmt.Errorf("seed must be an unsigned 64-bit integer, got %s", data)
+	}
+	*s = Seed(seed)
+	return nil
+}
+
+// soupTransform maps a cell onto its image within a width x height soup
+type soupTransform func(x, y, width, height int) (int, int)
+
+// soupSymmetry is a group of transforms a soup is invariant under
+type soupSymmetry struct {
+	transforms []soupTransform
+	// square tells if the transforms only fit soups as wide as they're high.
+	square bool
+}
+
+var (
+	rotate180 soupTransform = func(x, y, w, h int) (int, int) { return w - 1 - x, h - 1 - y }
+	rotate90  soupTransform = func(x, y, w, h int) (int, int) { return w - 1 - y, x }
+	rotate270 soupTransform = func(x, y, w, h int) (int, int) { return y, h - 1 - x }
+	flipX     soupTransform = func(x, y, w, h int) (int, int) { return w - 1 - x, y }
+	flipY     soupTransform = func(x, y, w, h int) (int, int) { return x, h - 1 - y }
+	transpose soupTransform = func(x, y, w, h int) (int, int) { return y, x }
+	// antiTranspose reflects about the diagonal from the bottom left to the top right corner.
+	antiTranspose soupTransform = func(x, y, w, h int) (int, int) { return w - 1 - y, h - 1 - x }
+)
+
+// soupSymmetries holds symmetry groups by name, as in apgsearch, centres of
+// symmetric soups depend on their sizes being odd or even.
+var soupSymmetries = map[string]soupSymmetry{
+	"C1":   {},
+	"C2":   {transforms: []soupTransform{rotate180}},
+	"C4":   {transforms: []soupTransform{rotate90, rotate180, rotate270}, square: true},
+	"D2_+": {transforms: []soupTransform{flipX}},
+	"D2_x": {transforms: []soupTransform{transpose}, square: true},
+	"D4_+": {transforms: []soupTransform{flipX, flipY, rotate180}},
+	"D4_x": {transforms: []soupTransform{transpose, antiTranspose, rotate180}, square: true},
+	"D8": {
+		transforms: []soupTransform{rotate90, rotate180, rotate270, flipX, flipY, transpose, antiTranspose},
+		square:     true,
+	},
+}
+
+// parseSoupSymmetry finds a symmetry group by its case insensitive name, returns its canonical name
+func parseSoupSymmetry(name string) (string, soupSymmetry, error) {
+	names := make([]string, 0, len(soupSymmetries))
+	for n, s := range soupSymmetries {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return n, s, nil
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return "", soupSymmetry{}, fmt.Errorf("unknown symmetry %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// Cells generates cells of the soup, rows first
+// Missing density, symmetry & seed get defaults, so the soup describes the cells exactly.
+func (s *Soup) Cells() ([][]bool, error) {
+	if s.Width <= 0 || s.Height <= 0 {
+		return nil, fmt.Errorf("soup width & height must be positive, got %dx%d", s.Width, s.Height)
+	}
+	// Checked before cells are allocated, by division as the product may overflow.
+	if s.Width > pattern.MaxCells/s.Height {
+		return nil, fmt.Errorf("soup of %dx%d cells exceeds the limit of %d cells", s.Width, s.Height, pattern.MaxCells)
+	}
+	if s.Density < 0 || s.Density > 1 {
+		return nil, fmt.Errorf("soup density must be between 0 and 1, got %g", s.Density)
+	}
+	if s.Density == 0 {
+		s.Density = DefaultDensity
+	}
+	if s.Symmetry == "" {
+		s.Symmetry = DefaultSymmetry
+	}
+	name, symmetry, err := parseSoupSymmetry(s.Symmetry)
+	if err != nil {
+		return nil, err
+	}
+	s.Symmetry = name
+	if symmetry.square && s.Width != s.Height {
+		return nil, fmt.Errorf("%s soups must be square, got %dx%d", s.Symmetry, s.Width, s.Height)
+	}
+	if s.Seed == nil {
+		clock := splitMix(time.Now().UnixNano())
+		seed := Seed(clock.next())
+		s.Seed = &seed
+	}
+
+	// Each cell takes the state of the first cell of its orbit in rows first
+	// order, only those draw from the random source.
+	source := splitMix(*s.Seed)
+	cells := make([][]bool, s.Height)
+	for y := range cells {
+		cells[y] = make([]bool, s.Width)
+		for x := range cells[y] {
+			fx, fy := x, y
+			for _, t := range symmetry.transforms {
+				tx, ty := t(x, y, s.Width, s.Height)
+				if ty < fy || (ty == fy && tx < fx) {
+					fx, fy = tx, ty
+				}
+			}
+			if fx == x && fy == y {
+				cells[y][x] = source.float64() < s.Density
+			} else {
+				cells[y][x] = cells[fy][fx]
+			}
+		}
+	}
+	return cells, nil
+}
+
+// cellsOn generates cells of the soup on the grid, only the square grid has symmetries
+func (s *Soup) cellsOn(grid Grid) ([][]bool, error) {
+	cells, err := s.Cells()
+	if err != nil {
+		return nil, err
+	}
+	if s.Symmetry != DefaultSymmetry && !isSquare(grid) {
+		return nil, fmt.Errorf("%s soups need the square grid", s.Symmetry)
+	}
+	return cells, nil
+}
+
+// NewSoup creates a Universe from cells generated from the soup
+func NewSoup(soup Soup, opts Options) (*Universe, error) {
+	if opts.Grid == nil {
+		opts.Grid = DefaultGrid
+	}
+	cells, err := soup.cellsOn(opts.Grid)
+	if err != nil {
+		return nil, err
+	}
+	u, err := New(cells, opts)
+	if err != nil {
+		return nil, err
+	}
+	u.Soup = &soup
+	return u, nil
+}
+
+// splitMix is a SplitMix64 random source, unlike math/rand it takes all 64
+// bits of a seed and its sequences never change between Go releases
+type splitMix uint64
+
+// next returns the next random number
+func (s *splitMix) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// float64 returns a random number within [0, 1)
+func (s *splitMix) float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
+
+// DecodeSoup decodes a Life universe with cells generated from a soup
+// Fields of the soup sit next to fields of the universe, e.g. the rule & the topology.
+func DecodeSoup(data []byte, maxPeriod int) (*Universe, error) {
+	var soup Soup
+	if err := json.Unmarshal(data, &soup); err != nil {
+		return nil, err
+	}
+	var u Universe
+	if err := u.decode(data, nil, &soup); err != nil {
+		return nil, err
+	}
+	u.setMaxPeriod(maxPeriod)
+	return &u, nil
+}
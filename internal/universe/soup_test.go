@@ -0,0 +1,124 @@
+package universe
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestSoupSymmetries(t *testing.T) {
+	for name, symmetry := range soupSymmetries {
+		sizes := [][2]int{{16, 16}, {17, 17}}
+		if !symmetry.square {
+			sizes = append(sizes, [2]int{16, 9}, [2]int{7, 12})
+		}
+		for _, size := range sizes {
+			seed := Seed(42)
+			soup := Soup{Width: size[0], Height: size[1], Symmetry: name, Seed: &seed}
+			cells, err := soup.Cells()
+			if err != nil {
+				t.Errorf("%s %dx%d: %s", name, size[0], size[1], err)
+				continue
+			}
+			for _, transform := range symmetry.transforms {
+				for y := range cells {
+					for x := range cells[y] {
+						tx, ty := transform(x, y, size[0], size[1])
+						if cells[ty][tx] != cells[y][x] {
+							t.Fatalf("%s %dx%d: cell (%d, %d) differs from its image (%d, %d)", name, size[0], size[1], x, y, tx, ty)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSoupErrors(t *testing.T) {
+	for _, soup := range []Soup{
+		{Width: 0, Height: 4},
+		{Width: 4, Height: -1},
+		{Width: 4, Height: 4, Density: 1.5},
+		{Width: 4, Height: 4, Symmetry: "C3"},
+		{Width: 4, Height: 5, Symmetry: "C4"},
+		{Width: 4, Height: 5, Symmetry: "D2_x"},
+		{Width: 4, Height: 5, Symmetry: "D4_x"},
+		{Width: 4, Height: 5, Symmetry: "D8"},
+		// Too many cells, also when their number overflows.
+		{Width: 1 << 13, Height: 1<<11 + 1},
+		{Width: math.MaxInt, Height: 2},
+		{Width: math.MaxInt / 2, Height: math.MaxInt / 2, Symmetry: "C4"},
+	} {
+		if _, err := soup.Cells(); err == nil {
+			t.Errorf("soup %+v succeeded", soup)
+		}
+	}
+	if _, err := NewSoup(Soup{Width: 4, Height: 4, Symmetry: "C2"}, Options{Rule: MustParseRule("B2/S34"), Grid: Hex{}}); err == nil {
+		t.Errorf("symmetric soup on the hex grid succeeded")
+	}
+}
+
+func TestSoupDefaults(t *testing.T) {
+	soup := Soup{Width: 8, Height: 8, Symmetry: "d4_+"}
+	if _, err := soup.Cells(); err != nil {
+		t.Fatal(err)
+	}
+	if soup.Density != DefaultDensity || soup.Symmetry != "D4_+" || soup.Seed == nil {
+		t.Errorf("soup %+v lacks defaults", soup)
+	}
+}
+
+func TestSoupReproducedFromJSON(t *testing.T) {
+	// Seeds beyond 2^53 don't fit into JavaScript numbers.
+	request := `{"width": 24, "height": 20, "density": 0.4, "symmetry": "C2", "seed": "18446744073709551557", "rule": "highlife"}`
+	u, err := DecodeSoup([]byte(request), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed struct {
+		Soup json.RawMessage `json:"soup"`
+		Rule string          `json:"rule"`
+	}
+	if err = json.Unmarshal(data, &streamed); err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(streamed.Soup, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["seed"] != "18446744073709551557" {
+		t.Errorf("seed streamed as %#v", fields["seed"])
+	}
+
+	// The streamed soup gives the same cells, also with the seed as a number.
+	var soup map[string]interface{}
+	if err = json.Unmarshal(streamed.Soup, &soup); err != nil {
+		t.Fatal(err)
+	}
+	soup["rule"] = streamed.Rule
+	for _, seed := range []interface{}{fields["seed"], json.Number("18446744073709551557")} {
+		soup["seed"] = seed
+		body, err := json.Marshal(soup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := DecodeSoup(body, 0)
+		if err != nil {
+			t.Fatalf("decoding %s: %s", body, err)
+		}
+		if !equalCells(again.Cells(), u.Cells()) || again.Rule != u.Rule {
+			t.Errorf("%s gave another universe", body)
+		}
+	}
+
+	for _, seed := range []string{`-1`, `"x"`, `1.5`, `"18446744073709551616"`} {
+		var s Seed
+		if err := json.Unmarshal([]byte(seed), &s); err == nil {
+			t.Errorf("seed %s decoded into %d", seed, s)
+		}
+	}
+}
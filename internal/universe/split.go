@@ -0,0 +1,173 @@
+package universe
+
+import (
+	"fmt"
+)
+
+// SplitOptions holds settings of a split
+type SplitOptions struct {
+	// Mode is either "grid", the default, or "components".
+	Mode string `json:"mode"`
+	// Columns & Rows of the grid of tiles.
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+	// Margin is the number of dead cells around every component.
+	Margin int `json:"margin"`
+}
+
+// maxSplitMargin limits the number of dead cells around a component.
+const maxSplitMargin = 1024
+
+// Split cuts the universe into new ones, a grid of tiles or one per connected
+// component of alive cells. Pieces inherit the colour, rule, topology,
+// engine, step & max period, colours & states of their cells, they start
+// from generation 0 without an ID.
+func (r *Universe) Split(opts SplitOptions) ([]*Universe, error) {
+	switch opts.Mode {
+	case "", "grid":
+		return r.splitGrid(opts.Columns, opts.Rows)
+	case "components":
+		return r.splitComponents(opts.Margin)
+	default:
+		return nil, fmt.Errorf("unknown split mode %q, expected grid or components", opts.Mode)
+	}
+}
+
+// splitGrid cuts the universe into columns x rows tiles
+// Tiles differ in size by one cell at most when the universe doesn't divide evenly.
+func (r *Universe) splitGrid(columns, rows int) ([]*Universe, error) {
+	if columns < 1 || rows < 1 || columns*rows < 2 {
+		return nil, fmt.Errorf("a grid needs at least 1 column & 1 row and 2 tiles, got %dx%d", columns, rows)
+	}
+	if columns > r.width || rows > r.height {
+		return nil, fmt.Errorf("%dx%d universe can't be cut into %dx%d tiles", r.width, r.height, columns, rows)
+	}
+	cells, states := r.Cells(), r.States()
+	pieces := make([]*Universe, 0, columns*rows)
+	for row := 0; row < rows; row++ {
+		top, bottom := row*r.height/rows, (row+1)*r.height/rows
+		for column := 0; column < columns; column++ {
+			left, right := column*r.width/columns, (column+1)*r.width/columns
+			tile := make([][]bool, bottom-top)
+			var colours, tileStates [][]uint8
+			if r.colours != nil {
+				colours = make([][]uint8, bottom-top)
+			}
+			if states != nil {
+				tileStates = make([][]uint8, bottom-top)
+			}
+			for y := range tile {
+				tile[y] = append([]bool(nil), cells[top+y][left:right]...)
+				if colours != nil {
+					colours[y] = append([]uint8(nil), r.colours.colours[top+y][left:right]...)
+				}
+				if tileStates != nil {
+					tileStates[y] = append([]uint8(nil), states[top+y][left:right]...)
+				}
+			}
+			piece, err := r.piece(tile, colours, tileStates)
+			if err != nil {
+				return nil, err
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+	return pieces, nil
+}
+
+// splitComponents cuts every 8-connected component into its own universe
+// Components fill their bounding box surrounded by margin dead cells, dying
+// cells of Generations rules belong to components like alive ones.
+func (r *Universe) splitComponents(margin int) ([]*Universe, error) {
+	if margin < 0 || margin > maxSplitMargin {
+		return nil, fmt.Errorf("margin must be between 0 and %d, got %d", maxSplitMargin, margin)
+	}
+	states := r.States()
+	cells := r.engine.aliveCells()
+	if states != nil {
+		cells = nil
+		for y, row := range states {
+			for x, state := range row {
+				if state != 0 {
+					cells = append(cells, [2]int{x, y})
+				}
+			}
+		}
+	}
+	unwrapped, origins := components(cells, r.width, r.height, r.Topology)
+	if len(unwrapped) == 0 {
+		return nil, fmt.Errorf("there are no alive cells to split")
+	}
+	pieces := make([]*Universe, 0, len(unwrapped))
+	for i, component := range unwrapped {
+		minX, minY, maxX, maxY := component[0][0], component[0][1], component[0][0], component[0][1]
+		for _, c := range component {
+			if c[0] < minX {
+				minX = c[0]
+			}
+			if c[0] > maxX {
+				maxX = c[0]
+			}
+			if c[1] < minY {
+				minY = c[1]
+			}
+			if c[1] > maxY {
+				maxY = c[1]
+			}
+		}
+		width, height := maxX-minX+1+2*margin, maxY-minY+1+2*margin
+		tile := make([][]bool, height)
+		var colours, tileStates [][]uint8
+		if r.colours != nil {
+			colours = make([][]uint8, height)
+		}
+		if states != nil {
+			tileStates = make([][]uint8, height)
+		}
+		for y := range tile {
+			tile[y] = make([]bool, width)
+			if colours != nil {
+				colours[y] = make([]uint8, width)
+			}
+			if tileStates != nil {
+				tileStates[y] = make([]uint8, width)
+			}
+		}
+		for j, c := range component {
+			x, y := c[0]-minX+margin, c[1]-minY+margin
+			origin := origins[i][j]
+			tile[y][x] = true
+			if colours != nil {
+				colours[y][x] = r.colours.colours[origin[1]][origin[0]]
+			}
+			if tileStates != nil {
+				tileStates[y][x] = states[origin[1]][origin[0]]
+				tile[y][x] = tileStates[y][x] == 1
+			}
+		}
+		piece, err := r.piece(tile, colours, tileStates)
+		if err != nil {
+			return nil, err
+		}
+		pieces = append(pieces, piece)
+	}
+	return pieces, nil
+}
+
+// piece creates a universe from a part of this one, states are given for Generations rules only
+func (r *Universe) piece(cells [][]bool, colours, states [][]uint8) (*Universe, error) {
+	opts := Options{
+		Colour:    r.Colour,
+		Rule:      r.Rule,
+		Topology:  r.Topology,
+		Grid:      r.Grid,
+		Engine:    r.Engine,
+		Step:      r.Step,
+		MaxPeriod: r.MaxPeriod,
+		States:    states,
+	}
+	if r.colours != nil {
+		opts.Palette, opts.Colours = r.colours.palette, colours
+	}
+	return New(cells, opts)
+}
@@ -0,0 +1,158 @@
+package universe
+
+import "testing"
+
+func TestSplitGrid(t *testing.T) {
+	u, err := New(parseCells(
+		"o...o",
+		".o...",
+		"..o..",
+		"...oo",
+	), Options{Rule: MustParseRule("B36/S23"), Topology: Bounded{}, Colour: "#123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pieces, err := u.Split(SplitOptions{Columns: 2, Rows: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 columns don't divide evenly, the right tiles are a cell wider.
+	want := [][][]bool{
+		parseCells("o.", ".o"),
+		parseCells("..o", "..."),
+		parseCells("..", ".."),
+		parseCells("o..", ".oo"),
+	}
+	if len(pieces) != len(want) {
+		t.Fatalf("%d pieces, want %d", len(pieces), len(want))
+	}
+	for i, piece := range pieces {
+		if !equalCells(piece.Cells(), want[i]) {
+			t.Errorf("piece %d: cells %v, want %v", i, piece.Cells(), want[i])
+		}
+		if piece.Rule != u.Rule || piece.Topology != u.Topology || piece.Colour != u.Colour || piece.ID != 0 {
+			t.Errorf("piece %d: %s didn't inherit settings of %s", i, piece, u)
+		}
+	}
+}
+
+func TestSplitKeepsStatesAndColours(t *testing.T) {
+	// Dying cells of a Generations rule.
+	states := [][]uint8{
+		{1, 2, 0, 0},
+		{0, 0, 0, 2},
+		{0, 0, 0, 1},
+	}
+	cells := make([][]bool, len(states))
+	for y, row := range states {
+		cells[y] = make([]bool, len(row))
+		for x, state := range row {
+			cells[y][x] = state == 1
+		}
+	}
+	u, err := New(cells, Options{Rule: MustParseRule("B2/S/3"), Topology: Bounded{}, States: states})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pieces, err := u.Split(SplitOptions{Columns: 2, Rows: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pieces[0].States(); got[0][0] != 1 || got[0][1] != 2 {
+		t.Errorf("left tile states %v", got)
+	}
+	if got := pieces[1].States(); got[1][1] != 2 || got[2][1] != 1 {
+		t.Errorf("right tile states %v", got)
+	}
+
+	// Dying cells belong to components of alive ones.
+	pieces, err = u.Split(SplitOptions{Mode: "components"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces) != 2 {
+		t.Fatalf("%d components, want 2", len(pieces))
+	}
+	for i, want := range [][][]uint8{{{1, 2}}, {{2}, {1}}} {
+		got := pieces[i].States()
+		if len(got) != len(want) || len(got[0]) != len(want[0]) {
+			t.Errorf("component %d: states %v, want %v", i, got, want)
+			continue
+		}
+		for y := range want {
+			for x := range want[y] {
+				if got[y][x] != want[y][x] {
+					t.Errorf("component %d: states %v, want %v", i, got, want)
+				}
+			}
+		}
+	}
+
+	// Species of a multi-colour rule.
+	u, err = New(parseCells("o..o"), Options{
+		Rule:     MustParseRule("immigration"),
+		Topology: Bounded{},
+		Colours:  [][]uint8{{1, 0, 0, 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pieces, err = u.Split(SplitOptions{Columns: 2, Rows: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if pieces[0].CellColour(0, 0) != 1 || pieces[1].CellColour(1, 0) != 0 || len(pieces[0].Palette()) != 2 {
+		t.Errorf("tiles lost species: %d & %d", pieces[0].CellColour(0, 0), pieces[1].CellColour(1, 0))
+	}
+}
+
+func TestSplitComponents(t *testing.T) {
+	// A block across the left & right edges of a torus & a blinker.
+	u, err := New(parseCells(
+		"o.....o",
+		"o.....o",
+		".......",
+		"..ooo..",
+	), Options{Rule: ConwayRule, Topology: Torus{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pieces, err := u.Split(SplitOptions{Mode: "components", Margin: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][][]bool{
+		parseCells("....", ".oo.", ".oo.", "...."),
+		parseCells(".....", ".ooo.", "....."),
+	}
+	if len(pieces) != len(want) {
+		t.Fatalf("%d components, want %d", len(pieces), len(want))
+	}
+	for _, piece := range pieces {
+		found := false
+		for _, cells := range want {
+			found = found || equalCells(piece.Cells(), cells)
+		}
+		if !found {
+			t.Errorf("unexpected component %v", piece.Cells())
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	u, err := New(parseCells("...", "...", "..."), Options{Rule: ConwayRule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []SplitOptions{
+		{Columns: 1, Rows: 1},
+		{Columns: 0, Rows: 2},
+		{Columns: 4, Rows: 1},
+		{Mode: "components"},
+		{Mode: "components", Margin: -1},
+		{Mode: "halves"},
+	} {
+		if _, err := u.Split(opts); err == nil {
+			t.Errorf("split %+v succeeded", opts)
+		}
+	}
+}
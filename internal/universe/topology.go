@@ -0,0 +1,175 @@
+package universe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Topology describes how the edges of a universe are glued together
+// Evolution resolves every neighbour outside the universe through it.
+type Topology interface {
+	// Name returns the name the topology is registered under.
+	Name() string
+	// Resolve maps (x, y) onto a cell of a width x height universe.
+	// ok is false when the coordinate falls off an edge, i.e. it's always dead.
+	Resolve(x, y, width, height int) (rx, ry int, ok bool)
+}
+
+// DefaultTopology is used when no topology is given.
+var DefaultTopology Topology = Torus{}
+
+// topologies holds all known topologies by name.
+var topologies = map[string]Topology{}
+
+// topologyAliases maps alternative names to registered topology names.
+var topologyAliases = map[string]string{
+	"plane":         "bounded",
+	"infinite":      "unbounded",
+	"toroidal":      "torus",
+	"klein-bottle":  "klein",
+	"cross-surface": "projective",
+}
+
+func init() {
+	for _, t := range []Topology{Bounded{}, Torus{}, Cylinder{}, Klein{}, Projective{}, Unbounded{}} {
+		RegisterTopology(t)
+	}
+}
+
+// RegisterTopology makes a topology available by its name
+func RegisterTopology(t Topology) {
+	topologies[t.Name()] = t
+}
+
+// ParseTopology finds a registered topology by its name or alias
+func ParseTopology(name string) (Topology, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := topologyAliases[name]; ok {
+		name = alias
+	}
+	t, ok := topologies[name]
+	if !ok {
+		names := make([]string, 0, len(topologies))
+		for n := range topologies {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown topology %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return t, nil
+}
+
+// Bounded is a bounded plane, everything beyond the edges is dead
+type Bounded struct{}
+
+// Name returns the name of the topology
+func (Bounded) Name() string { return "bounded" }
+
+// Resolve maps a coordinate onto the universe
+func (Bounded) Resolve(x, y, width, height int) (int, int, bool) {
+	if x < 0 || y < 0 || x >= width || y >= height {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// Unbounded is an infinite plane, the universe is only a window onto it
+// Only engines simulating the whole plane support it, for everything else it's
+// indistinguishable from Bounded.
+type Unbounded struct{}
+
+// Name returns the name of the topology
+func (Unbounded) Name() string { return "unbounded" }
+
+// Resolve maps a coordinate onto the window, cells outside it are unknown
+func (Unbounded) Resolve(x, y, width, height int) (int, int, bool) {
+	return Bounded{}.Resolve(x, y, width, height)
+}
+
+// Torus wraps both axes
+type Torus struct{}
+
+// Name returns the name of the topology
+func (Torus) Name() string { return "torus" }
+
+// Resolve maps a coordinate onto the universe
+func (Torus) Resolve(x, y, width, height int) (int, int, bool) {
+	return floorMod(x, width), floorMod(y, height), true
+}
+
+// Cylinder wraps the horizontal axis, top & bottom edges are dead
+type Cylinder struct{}
+
+// Name returns the name of the topology
+func (Cylinder) Name() string { return "cylinder" }
+
+// Resolve maps a coordinate onto the universe
+func (Cylinder) Resolve(x, y, width, height int) (int, int, bool) {
+	if y < 0 || y >= height {
+		return 0, 0, false
+	}
+	return floorMod(x, width), y, true
+}
+
+// Klein is a Klein bottle, crossing the top or bottom edge mirrors the horizontal axis
+type Klein struct{}
+
+// Name returns the name of the topology
+func (Klein) Name() string { return "klein" }
+
+// Resolve maps a coordinate onto the universe
+func (Klein) Resolve(x, y, width, height int) (int, int, bool) {
+	if floorDiv(y, height)%2 != 0 {
+		x = width - 1 - x
+	}
+	return floorMod(x, width), floorMod(y, height), true
+}
+
+// Projective is a real projective plane (cross-surface), crossing any edge
+// mirrors the other axis
+type Projective struct{}
+
+// Name returns the name of the topology
+func (Projective) Name() string { return "projective" }
+
+// Resolve maps a coordinate onto the universe
+func (Projective) Resolve(x, y, width, height int) (int, int, bool) {
+	if floorDiv(x, width)%2 != 0 {
+		y = height - 1 - y
+	}
+	if floorDiv(y, height)%2 != 0 {
+		x = width - 1 - x
+	}
+	return floorMod(x, width), floorMod(y, height), true
+}
+
+// isTranslationInvariant tells if shifted cells evolve the same way wherever
+// they are, only then a pattern repeating itself shifted is a spaceship
+// Elsewhere it eventually hits an edge & changes.
+func isTranslationInvariant(t Topology) bool {
+	switch t.(type) {
+	case Torus, Unbounded:
+		return true
+	default:
+		return false
+	}
+}
+
+// floorDiv divides rounding towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// floorMod returns a non-negative remainder
+func floorMod(a, b int) int {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
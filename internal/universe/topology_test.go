@@ -0,0 +1,102 @@
+package universe
+
+import "testing"
+
+func TestTopologyResolve(t *testing.T) {
+	const width, height = 4, 3
+	tests := []struct {
+		topology Topology
+		x, y     int
+		wantX    int
+		wantY    int
+		wantOK   bool
+	}{
+		{Bounded{}, 0, 0, 0, 0, true},
+		{Bounded{}, -1, 0, 0, 0, false},
+		{Bounded{}, 0, 3, 0, 0, false},
+		{Unbounded{}, 4, 1, 0, 0, false},
+		{Torus{}, -1, -1, 3, 2, true},
+		{Torus{}, 4, 3, 0, 0, true},
+		{Torus{}, 9, -4, 1, 2, true},
+		{Cylinder{}, -1, 1, 3, 1, true},
+		{Cylinder{}, 4, 2, 0, 2, true},
+		{Cylinder{}, 1, -1, 0, 0, false},
+		{Cylinder{}, 1, 3, 0, 0, false},
+		// Crossing the top or bottom edge of a Klein bottle mirrors x.
+		{Klein{}, -1, 1, 3, 1, true},
+		{Klein{}, 0, -1, 3, 2, true},
+		{Klein{}, 1, 3, 2, 0, true},
+		{Klein{}, 1, 6, 1, 0, true},
+		// Crossing any edge of a projective plane mirrors the other axis.
+		{Projective{}, -1, 0, 3, 2, true},
+		{Projective{}, 4, 2, 0, 0, true},
+		{Projective{}, 0, -1, 3, 2, true},
+		{Projective{}, 1, 3, 2, 0, true},
+		{Projective{}, -1, -1, 0, 0, true},
+	}
+	for _, tt := range tests {
+		x, y, ok := tt.topology.Resolve(tt.x, tt.y, width, height)
+		if ok != tt.wantOK || (ok && (x != tt.wantX || y != tt.wantY)) {
+			t.Errorf(
+				"%s.Resolve(%d, %d) = (%d, %d, %t), want (%d, %d, %t)",
+				tt.topology.Name(), tt.x, tt.y, x, y, ok, tt.wantX, tt.wantY, tt.wantOK,
+			)
+		}
+	}
+}
+
+func TestParseTopology(t *testing.T) {
+	for name, want := range map[string]string{
+		"torus":         "torus",
+		" Torus ":       "torus",
+		"plane":         "bounded",
+		"infinite":      "unbounded",
+		"klein-bottle":  "klein",
+		"cross-surface": "projective",
+		"cylinder":      "cylinder",
+	} {
+		topology, err := ParseTopology(name)
+		if err != nil {
+			t.Errorf("ParseTopology(%q) failed: %s", name, err)
+			continue
+		}
+		if topology.Name() != want {
+			t.Errorf("ParseTopology(%q) = %s, want %s", name, topology.Name(), want)
+		}
+	}
+	if _, err := ParseTopology("sphere"); err == nil {
+		t.Errorf("ParseTopology(\"sphere\") succeeded")
+	}
+}
+
+func TestTopologyEvolution(t *testing.T) {
+	// A blinker across the left & right edges.
+	cells := parseCells(".....", "oo..o", ".....")
+	tests := []struct {
+		topology Topology
+		want     [][]bool
+	}{
+		// Edge cells see nothing beyond the edge & die.
+		{Bounded{}, parseCells(".....", ".....", ".....")},
+		// A horizontal blinker centred on the wrapped column turns vertical.
+		{Torus{}, parseCells("o....", "o....", "o....")},
+		{Cylinder{}, parseCells("o....", "o....", "o....")},
+	}
+	for _, tt := range tests {
+		got := evolved(t, cells, Options{Rule: ConwayRule, Topology: tt.topology}, 1)
+		if !equalCells(got, tt.want) {
+			t.Errorf("%s: evolved into %v, want %v", tt.topology.Name(), got, tt.want)
+		}
+	}
+
+	// A glider on a torus is back where it started after 4 generations per cell of the universe.
+	glider := parseCells(".o....", "..o...", "ooo...", "......", "......", "......")
+	if got := evolved(t, glider, Options{Rule: ConwayRule, Topology: Torus{}}, 24); !equalCells(got, glider) {
+		t.Errorf("glider on a torus didn't come back: %v", got)
+	}
+	// On a bounded plane it turns into a block in the corner.
+	block := parseCells("......", "......", "......", "......", "....oo", "....oo")
+	if got := evolved(t, glider, Options{Rule: ConwayRule, Topology: Bounded{}}, 24); !equalCells(got, block) {
+		t.Errorf("glider on a bounded plane didn't turn into a block: %v", got)
+	}
+}
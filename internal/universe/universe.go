@@ -1,8 +1,10 @@
 package universe
 
 import (
+	"encoding/json"
 	"fmt"
-	"hash/fnv"
+	"github.com/ride90/game-of-life/internal/pattern"
+	"math"
 	"strings"
 	"time"
 )
@@ -15,43 +17,363 @@ const (
 )
 
 // Universe represents an individual cellular universe
+// Cells are owned by an engine, its JSON representation is described by universeJSON.
 type Universe struct {
-	// TODO: Think of a decomposition json-specific fields.
-	//  - https://attilaolah.eu/2014/09/10/json-and-struct-composition-in-go/
-	Matrix     [][]bool  `json:"cells"`
-	Colour     string    `json:"colour"`
-	IsStatic   bool      `json:"-"`
-	StaticFrom time.Time `json:"-"`
-	// TODO: is `json:"-"` redundant?
-	generationNumber int    `json:"-"`
-	aliveCellsCount  int    `json:"-"`
-	matrixHash       uint64 `json:"-"`
+	// ID identifies the universe within a multiverse, 0 until it's added to one.
+	ID       uint64
+	Colour   string
+	Rule     Rule
+	Topology Topology
+	Grid     Grid
+	Engine   string
+	Step     uint
+	// MaxPeriod is the longest period, in ticks, looked for.
+	MaxPeriod int
+	// IsStatic tells if the universe doesn't change anymore, no sense to compute it.
+	IsStatic bool
+	// Period is the number of generations after which the universe repeats
+	// itself, 0 while no periodic behaviour is found.
+	Period int
+	// PeriodFrom is the generation the periodic behaviour was first seen at.
+	PeriodFrom int
+	// Velocity is the displacement per period of a universe holding a single spaceship.
+	Velocity Velocity
+	// SettledFrom is the time the universe became static or periodic.
+	SettledFrom time.Time
+	// Soup is the random soup cells were generated from, nil for other universes.
+	Soup *Soup
+	// Rewinds counts rewinds of the universe, clients redraw it when it changes.
+	Rewinds          int
+	engine           engine
+	colours          *colourPlane
+	detector         *periodDetector
+	census           Census
+	history          *history
+	width            int
+	height           int
+	generationNumber int
+	aliveCellsCount  int
+}
+
+// Options holds settings of a new Universe
+type Options struct {
+	Colour   string
+	Rule     Rule
+	Topology Topology // Engine's default topology if nil
+	Grid     Grid     // DefaultGrid if nil
+	Engine   string   // Default engine of the rule & the grid if empty
+	// Step makes every Evolve jump 2^Step generations, if the engine supports it.
+	Step uint
+	// MaxPeriod is the longest period looked for, DefaultMaxPeriod if 0.
+	MaxPeriod int
+	// Palette makes a multi-colour universe, Colours holds palette indices of
+	// cells, rows first, all cells take the first colour if nil.
+	Palette []string
+	Colours [][]uint8
+	// States holds states of cells of a Generations rule, rows first, cells
+	// are alive or dead as given if nil.
+	States [][]uint8
+}
+
+// New creates a Universe from a matrix of cells, rows first
+func New(cells [][]bool, opts Options) (*Universe, error) {
+	if len(cells) == 0 || len(cells[0]) == 0 {
+		return nil, fmt.Errorf("cells must not be empty")
+	}
+	for y := range cells {
+		if len(cells[y]) != len(cells[0]) {
+			return nil, fmt.Errorf("cells row %d has %d cells, expected %d", y, len(cells[y]), len(cells[0]))
+		}
+	}
+	if opts.Grid == nil {
+		opts.Grid = DefaultGrid
+	}
+	opts.Engine = strings.ToLower(strings.TrimSpace(opts.Engine))
+	if opts.Engine == "" {
+		opts.Engine = defaultEngineFor(opts.Rule, opts.Grid)
+	}
+	spec, err := getEngineSpec(opts.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Topology == nil {
+		opts.Topology = spec.topology
+	}
+	e, err := spec.factory(cells, opts.Rule, opts.Topology, opts.Grid)
+	if err != nil {
+		return nil, err
+	}
+	if opts.States != nil {
+		se, ok := e.(multiStateEngine)
+		if !ok {
+			return nil, fmt.Errorf("%s engine has no cell states", opts.Engine)
+		}
+		if err = se.setStates(opts.States); err != nil {
+			return nil, err
+		}
+	}
+	if opts.MaxPeriod == 0 {
+		opts.MaxPeriod = DefaultMaxPeriod
+	}
+	if opts.Step > 0 && e.maxStep() == 0 {
+		return nil, fmt.Errorf("%s engine can't jump generations, step must be 0", opts.Engine)
+	}
+	if opts.Step > e.maxStep() {
+		return nil, fmt.Errorf("%s engine supports steps up to %d, got %d", opts.Engine, e.maxStep(), opts.Step)
+	}
+
+	var colours *colourPlane
+	if (opts.Rule.Species > 0 || len(opts.Palette) > 0) && !isSquare(opts.Grid) {
+		return nil, fmt.Errorf("multi-colour universes need the square grid")
+	}
+	if opts.Rule.Species > 0 {
+		if opts.Palette == nil {
+			opts.Palette = speciesPalette[:opts.Rule.Species]
+		}
+		if len(opts.Palette) != opts.Rule.Species {
+			return nil, fmt.Errorf(
+				"%s rule needs a palette of %d colours, got %d", opts.Rule, opts.Rule.Species, len(opts.Palette),
+			)
+		}
+	}
+	if opts.Colours != nil && len(opts.Palette) == 0 {
+		return nil, fmt.Errorf("colours require a palette")
+	}
+	if len(opts.Palette) > 0 {
+		if _, unbounded := opts.Topology.(Unbounded); unbounded || opts.Step > 0 {
+			return nil, fmt.Errorf("multi-colour universes can't jump generations nor be unbounded")
+		}
+		if opts.Colours == nil {
+			opts.Colours = make([][]uint8, len(cells))
+			for y := range opts.Colours {
+				opts.Colours[y] = make([]uint8, len(cells[y]))
+			}
+		}
+		if colours, err = newColourPlane(opts.Palette, opts.Colours, e.cells(), opts.Rule.Species > 0); err != nil {
+			return nil, err
+		}
+	}
+
+	u := &Universe{
+		Colour:    opts.Colour,
+		Rule:      opts.Rule,
+		Topology:  opts.Topology,
+		Grid:      opts.Grid,
+		Engine:    opts.Engine,
+		Step:      opts.Step,
+		MaxPeriod: opts.MaxPeriod,
+		engine:    e,
+		colours:   colours,
+		width:     len(cells[0]),
+		height:    len(cells),
+	}
+	u.UpdateStats()
+	return u, nil
+}
+
+// options returns settings the universe was created with
+func (r *Universe) options() Options {
+	opts := Options{
+		Colour:    r.Colour,
+		Rule:      r.Rule,
+		Topology:  r.Topology,
+		Grid:      r.Grid,
+		Engine:    r.Engine,
+		Step:      r.Step,
+		MaxPeriod: r.MaxPeriod,
+	}
+	if r.colours != nil {
+		opts.Palette, opts.Colours = r.colours.palette, r.colours.colours
+	}
+	return opts
+}
+
+// Kind returns the kind of automata Life universes are
+func (r *Universe) Kind() string {
+	return DefaultKind
+}
+
+// SetID sets the ID identifying the universe within a multiverse
+func (r *Universe) SetID(id uint64) {
+	r.ID = id
+}
+
+// setMaxPeriod sets the longest period looked for, if it's given
+func (r *Universe) setMaxPeriod(maxPeriod int) {
+	if maxPeriod > 0 {
+		r.MaxPeriod = maxPeriod
+	}
+}
+
+// Stats returns the size, the population & the convergence of the universe
+func (r *Universe) Stats() Stats {
+	return Stats{
+		ID:          r.ID,
+		Width:       r.width,
+		Height:      r.height,
+		Generation:  r.generationNumber,
+		Alive:       r.aliveCellsCount,
+		Settled:     r.IsSettled(),
+		SettledFrom: r.SettledFrom,
+	}
+}
+
+// Hash returns a position dependent hash of cells & their colours
+func (r *Universe) Hash() uint64 {
+	hash := r.engine.hash()
+	if r.colours != nil {
+		// Colours may keep changing while cells repeat themselves.
+		hash = mixHash(hash, r.colours.hash())
+	}
+	return hash
+}
+
+// Encode returns the JSON representation of the universe
+func (r *Universe) Encode() ([]byte, error) {
+	return json.Marshal(r)
 }
 
 // String returns a string representation of the Universe
 func (r *Universe) String() string {
 	return fmt.Sprintf(
-		"Colour: %s Static: %t Generation %d Alive: %d",
-		r.Colour, r.IsStatic, r.generationNumber, r.aliveCellsCount,
+		"Colour: %s Rule: %s Topology: %s Grid: %s Engine: %s Size: %dx%d Converged: %q Generation %d Alive: %d",
+		r.Colour, r.Rule, r.Topology.Name(), r.Grid.Name(), r.Engine, r.width, r.height, r.Convergence(), r.generationNumber, r.aliveCellsCount,
 	)
 }
 
+// Width returns the number of cells in a row
+func (r *Universe) Width() int {
+	return r.width
+}
+
+// Height returns the number of rows
+func (r *Universe) Height() int {
+	return r.height
+}
+
+// Cell tells if the cell at (x, y) is alive
+func (r *Universe) Cell(x, y int) bool {
+	return r.engine.cell(x, y)
+}
+
+// Cells returns a copy of the cells matrix, rows first
+func (r *Universe) Cells() [][]bool {
+	return r.engine.cells()
+}
+
+// Pattern returns the universe's cells & rule for export
+// On an unbounded plane it's the bounding box of alive cells, otherwise the whole universe.
+// Patterns are two-state: dying cells of Generations rules are exported dead & species
+// of multi-colour rules are lost, alive cells of every colour are just alive.
+func (r *Universe) Pattern() (*pattern.Pattern, error) {
+	if _, ok := r.Topology.(Unbounded); !ok {
+		return &pattern.Pattern{Width: r.width, Height: r.height, Cells: r.Cells(), Rule: r.Rule.String()}, nil
+	}
+	cells := r.engine.aliveCells()
+	if len(cells) == 0 {
+		return &pattern.Pattern{Width: 1, Height: 1, Cells: [][]bool{{false}}, Rule: r.Rule.String()}, nil
+	}
+	minX, minY, maxX, maxY := cells[0][0], cells[0][1], cells[0][0], cells[0][1]
+	for _, c := range cells {
+		if c[0] < minX {
+			minX = c[0]
+		}
+		if c[0] > maxX {
+			maxX = c[0]
+		}
+		if c[1] < minY {
+			minY = c[1]
+		}
+		if c[1] > maxY {
+			maxY = c[1]
+		}
+	}
+	width, height := maxX-minX+1, maxY-minY+1
+	if width > pattern.MaxCells || height > pattern.MaxCells || width*height > pattern.MaxCells {
+		return nil, fmt.Errorf(
+			"alive cells span %dx%d cells, more than the limit of %d cells", width, height, pattern.MaxCells,
+		)
+	}
+	matrix := make([][]bool, height)
+	for y := range matrix {
+		matrix[y] = make([]bool, width)
+	}
+	for _, c := range cells {
+		matrix[c[1]-minY][c[0]-minX] = true
+	}
+	return &pattern.Pattern{Width: width, Height: height, Cells: matrix, Rule: r.Rule.String()}, nil
+}
+
+// States returns states of cells of a Generations universe, rows first, nil otherwise
+// 0 is dead, 1 alive, higher ones are dying.
+func (r *Universe) States() [][]uint8 {
+	se, ok := r.engine.(multiStateEngine)
+	if !ok || !r.Rule.IsGenerations() {
+		return nil
+	}
+	return se.states()
+}
+
+// Palette returns colours of a multi-colour universe, nil otherwise
+func (r *Universe) Palette() []string {
+	if r.colours == nil {
+		return nil
+	}
+	return r.colours.palette
+}
+
+// CellColour returns the palette index of the cell at (x, y) of a multi-colour universe
+func (r *Universe) CellColour(x, y int) uint8 {
+	if r.colours == nil {
+		return 0
+	}
+	return r.colours.colours[y][x]
+}
+
 // UpdateStats updates the count of alive cells in the Universe
+// A census of objects is taken once the universe settles.
 func (r *Universe) UpdateStats() {
-	for _, row := range r.Matrix {
-		for _, cell := range row {
-			if cell == aliveValue {
-				r.aliveCellsCount++
-			}
-		}
+	r.aliveCellsCount = r.engine.population()
+	if r.IsSettled() && r.census == nil {
+		r.census = r.takeCensus()
 	}
 }
 
+// Census returns objects the universe settled into, or objects of the
+// current generation if it's still evolving
+func (r *Universe) Census() Census {
+	return r.PrepareCensus()()
+}
+
+// PrepareCensus returns a function returning the census Census returns
+// Only alive cells are copied right away, objects are identified by the
+// function, which doesn't touch the universe & may run while it evolves.
+func (r *Universe) PrepareCensus() func() Census {
+	if r.census != nil {
+		census := r.census
+		return func() Census { return census }
+	}
+	maxPeriod := r.MaxPeriod
+	if !r.Rule.isTwoStateMoore() || !isSquare(r.Grid) {
+		// Objects can't be evolved alone, they're identified by their shape only.
+		maxPeriod = 0
+	}
+	cells, width, height, topology, rule := r.engine.aliveCells(), r.width, r.height, r.Topology, r.Rule
+	return func() Census {
+		return takeCensus(cells, width, height, topology, rule, maxPeriod)
+	}
+}
+
+// takeCensus splits alive cells into objects & counts them
+func (r *Universe) takeCensus() Census {
+	return r.PrepareCensus()()
+}
+
 // RenderMatrix renders the Universe matrix as a string
 // Used for debug purposes.
 func (r *Universe) RenderMatrix() string {
 	var matrixStringBuilder strings.Builder
-	for _, row := range r.Matrix {
+	for _, row := range r.Cells() {
 		for _, cell := range row {
 			if cell == aliveValue {
 				matrixStringBuilder.WriteString(aliveRender)
@@ -64,116 +386,94 @@ func (r *Universe) RenderMatrix() string {
 	return matrixStringBuilder.String()
 }
 
-// Evolve evolves the Universe according to Conway's Game of Life rules
+// IsSettled tells if the universe became static, periodic or a lonely spaceship
+func (r *Universe) IsSettled() bool {
+	return r.Period > 0
+}
+
+// Convergence describes what a settled universe converged to
+func (r *Universe) Convergence() string {
+	switch {
+	case !r.IsSettled():
+		return ""
+	case r.aliveCellsCount == 0:
+		return "empty"
+	case r.IsStatic:
+		return "still life"
+	case !r.Velocity.IsZero():
+		return "spaceship with velocity " + r.Velocity.Format(r.Period)
+	default:
+		return fmt.Sprintf("oscillator with period %d", r.Period)
+	}
+}
+
+// Evolve evolves the Universe according to its birth/survival Rule
+// Previous generations are kept in the history, if the universe has one. A
+// universe whose generation number would overflow doesn't evolve anymore.
 func (r *Universe) Evolve() {
-	// No sense to compute static universe.
-	if r.IsStatic {
-		r.generationNumber++
+	if r.generationNumber > math.MaxInt-1<<r.Step {
 		return
 	}
+	if !r.IsSettled() {
+		r.detectPeriod()
+	}
+	r.keepFrame()
 
-	// Calculate matrix hash & compare with a previous one.
-	// If hashes are equal then it means universe is static, and it's not going
-	// to evolve, in this case no sense to compute it anymore.
-	matrixHash := getMatrixHash(r.Matrix)
-	if matrixHash == r.matrixHash {
-		r.IsStatic = true
-		r.StaticFrom = time.Now().UTC()
-		r.generationNumber++
+	// No sense to compute static universe.
+	if r.IsStatic {
+		r.generationNumber += 1 << r.Step
+		r.record(false)
 		return
 	}
-	r.matrixHash = matrixHash
-
-	// Create a second matrix which represents a next generation.
-	var nextGenMatrix [][]bool
-	nextGenMatrix = make([][]bool, len(r.Matrix))
-	for i := range nextGenMatrix {
-		nextGenMatrix[i] = make([]bool, len(r.Matrix[i]))
-		copy(nextGenMatrix[i], r.Matrix[i])
-	}
-
-	// Run game of live algorithm.
-	r.aliveCellsCount = 0
-	for y := range r.Matrix {
-		for x := range r.Matrix[y] {
-			neighborsCount := r.neighboursCount(x, y)
-			if r.Matrix[y][x] == aliveValue {
-				if neighborsCount < 2 || neighborsCount > 3 {
-					nextGenMatrix[y][x] = deadValue
-				}
-			} else if neighborsCount == 3 {
-				nextGenMatrix[y][x] = aliveValue
-			}
-			if nextGenMatrix[y][x] == aliveValue {
-				r.aliveCellsCount++
-			}
-		}
+
+	r.engine.step(r.Step)
+	if r.colours != nil {
+		r.colours.evolve(r.engine, r.Topology)
 	}
-	r.Matrix = nextGenMatrix
-	r.generationNumber++
+	r.UpdateStats()
+	r.generationNumber += 1 << r.Step
+	r.record(true)
 }
 
-// neighboursCount method calculates the number of live neighbors for a given cell.
-func (r *Universe) neighboursCount(x, y int) int {
-	var ex, wx, ny, sy, neighbours int
-	if x != len(r.Matrix[y])-1 {
-		ex = x + 1
-	} else {
-		ex = 0
-	}
-	if x != 0 {
-		wx = x - 1
-	} else {
-		wx = len(r.Matrix[y]) - 1
-	}
-	if y != 0 {
-		ny = y - 1
-	} else {
-		ny = len(r.Matrix) - 1
-	}
-	if y != len(r.Matrix)-1 {
-		sy = y + 1
-	} else {
-		sy = 0
+// detectPeriod looks for the current generation among recent ones
+// A match means the universe repeats itself forever, possibly shifted, when the
+// match is the previous tick at the same position it's static. Shifted matches
+// only count on topologies without edges. Jumping universes report a multiple
+// of the true period.
+func (r *Universe) detectPeriod() {
+	if r.detector == nil {
+		r.detector = newPeriodDetector(r.MaxPeriod)
 	}
-	if r.Matrix[ny][wx] == aliveValue {
-		neighbours++
-	}
-	if r.Matrix[ny][x] == aliveValue {
-		neighbours++
-	}
-	if r.Matrix[ny][ex] == aliveValue {
-		neighbours++
-	}
-	if r.Matrix[y][wx] == aliveValue {
-		neighbours++
-	}
-	if r.Matrix[y][ex] == aliveValue {
-		neighbours++
-	}
-	if r.Matrix[sy][wx] == aliveValue {
-		neighbours++
-	}
-	if r.Matrix[sy][ex] == aliveValue {
-		neighbours++
+	s := signature{generation: r.generationNumber, hash: r.Hash()}
+	switch se, ok := r.engine.(multiStateEngine); {
+	case !isTranslationInvariant(r.Topology):
+		// Shifted cells hit an edge sooner or later, only the hash tells.
+	case ok:
+		// Dying cells & parities of cells are a part of the shape.
+		s.shape, s.x, s.y = se.shape()
+	case r.aliveCellsCount > 0 && r.aliveCellsCount <= maxShapePopulation:
+		cells := r.engine.aliveCells()
+		if _, ok := r.Topology.(Torus); ok {
+			unwrapTorus(cells, r.width, r.height)
+		}
+		s.shape, s.x, s.y = shapeOf(cells)
+		if r.colours != nil {
+			// Colours are a part of the shape, as they're a part of the hash.
+			s.shape = r.colours.shape(s.shape, cells)
+		}
 	}
-	if r.Matrix[sy][x] == aliveValue {
-		neighbours++
+	match, ok := r.detector.observe(s)
+	if !ok {
+		return
 	}
-	return neighbours
-}
-
-// getMatrixHash calculates a hash value for the given matrix
-func getMatrixHash(matrix [][]bool) uint64 {
-	hasher := fnv.New64a()
-	for y := range matrix {
-		for x := range matrix[y] {
-			if matrix[y][x] {
-				hasher.Write([]byte{1})
-			} else {
-				hasher.Write([]byte{0})
-			}
-		}
+	r.Period = r.generationNumber - match.generation
+	r.PeriodFrom = match.generation
+	r.Velocity = match.velocity
+	if _, ok := r.Topology.(Torus); ok {
+		r.Velocity = r.Velocity.wrap(r.width, r.height)
 	}
-	return hasher.Sum64()
+	r.IsStatic = r.Period == 1<<r.Step && r.Velocity.IsZero()
+	r.SettledFrom = time.Now().UTC()
+	r.detector = nil
+	r.UpdateStats()
 }
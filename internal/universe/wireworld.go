@@ -0,0 +1,291 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Wireworld cell states.
+const (
+	wireEmpty uint8 = iota
+	wireHead
+	wireTail
+	wireConductor
+	wireStates
+)
+
+// wireworldPalette holds colours of electron heads, tails & conductors.
+var wireworldPalette = []string{"#0099ff", "#fa725a", "#b87333"}
+
+// Wireworld simulates electrons running along conductors, a universe for logic circuits
+// Heads become tails, tails become conductors and conductors become heads when
+// 1 or 2 of their 8 neighbours are heads.
+type Wireworld struct {
+	ID       uint64
+	Colour   string
+	Topology Topology
+	// MaxPeriod is the longest period, in ticks, looked for.
+	MaxPeriod   int
+	Period      int
+	PeriodFrom  int
+	SettledFrom time.Time
+	width       int
+	height      int
+	// cur & next hold states of cells, rows first.
+	cur        []uint8
+	next       []uint8
+	generation int
+	heads      int
+	detector   *periodDetector
+}
+
+// wireworldJSON is the JSON representation of a Wireworld
+type wireworldJSON struct {
+	Kind     string `json:"kind"`
+	Colour   string `json:"colour"`
+	Topology string `json:"topology"`
+	// States of cells: 0 is empty, 1 an electron head, 2 an electron tail, 3 a conductor.
+	States [][]int `json:"states"`
+	// Read only fields for rendering, non-empty cells & palette indices of their states.
+	Matrix     [][]bool `json:"cells"`
+	Palette    []string `json:"palette"`
+	Colours    [][]int  `json:"colours"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	ID         uint64   `json:"id"`
+	Generation int      `json:"generation"`
+	Alive      int      `json:"alive"`
+	IsStatic   bool     `json:"static"`
+	Period     int      `json:"period"`
+	PeriodFrom int      `json:"period_from"`
+	Converged  string   `json:"converged"`
+}
+
+// NewWireworld creates a Wireworld from states of cells, rows first
+func NewWireworld(states [][]uint8, colour string, topology Topology) (*Wireworld, error) {
+	if len(states) == 0 || len(states[0]) == 0 {
+		return nil, fmt.Errorf("states must not be empty")
+	}
+	if topology == nil {
+		topology = DefaultTopology
+	}
+	if _, ok := topology.(Unbounded); ok {
+		return nil, fmt.Errorf("wireworld can't evolve %q topology", topology.Name())
+	}
+	height, width := len(states), len(states[0])
+	w := &Wireworld{
+		Colour:    colour,
+		Topology:  topology,
+		MaxPeriod: DefaultMaxPeriod,
+		width:     width,
+		height:    height,
+		cur:       make([]uint8, width*height),
+		next:      make([]uint8, width*height),
+	}
+	for y, row := range states {
+		if len(row) != width {
+			return nil, fmt.Errorf("states row %d has %d cells, expected %d", y, len(row), width)
+		}
+		for x, state := range row {
+			if state >= wireStates {
+				return nil, fmt.Errorf("state of cell (%d, %d) must be between 0 and %d, got %d", x, y, wireStates-1, state)
+			}
+		}
+		copy(w.cur[y*width:], row)
+	}
+	w.countHeads()
+	return w, nil
+}
+
+// decodeWireworld decodes a Wireworld from its JSON representation
+func decodeWireworld(data []byte, maxPeriod int) (Automaton, error) {
+	var u wireworldJSON
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	var topology Topology
+	if u.Topology != "" {
+		var err error
+		if topology, err = ParseTopology(u.Topology); err != nil {
+			return nil, err
+		}
+	}
+	states := make([][]uint8, len(u.States))
+	for y, row := range u.States {
+		states[y] = make([]uint8, len(row))
+		for x, state := range row {
+			if state < 0 || state >= int(wireStates) {
+				return nil, fmt.Errorf("state of cell (%d, %d) must be between 0 and %d, got %d", x, y, wireStates-1, state)
+			}
+			states[y][x] = uint8(state)
+		}
+	}
+	w, err := NewWireworld(states, u.Colour, topology)
+	if err != nil {
+		return nil, err
+	}
+	if maxPeriod > 0 {
+		w.MaxPeriod = maxPeriod
+	}
+	return w, nil
+}
+
+// Kind returns the name of the kind
+func (w *Wireworld) Kind() string {
+	return "wireworld"
+}
+
+// SetID sets the ID identifying the universe within a multiverse
+func (w *Wireworld) SetID(id uint64) {
+	w.ID = id
+}
+
+// String returns a string representation of the Wireworld
+func (w *Wireworld) String() string {
+	return fmt.Sprintf(
+		"Wireworld Colour: %s Topology: %s Size: %dx%d Converged: %q Generation %d Heads: %d",
+		w.Colour, w.Topology.Name(), w.width, w.height, w.convergence(), w.generation, w.heads,
+	)
+}
+
+// Stats returns the size, the number of electron heads & the convergence of the Wireworld
+// Only circuits without electrons are settled, clocked ones are what circuits are for.
+func (w *Wireworld) Stats() Stats {
+	return Stats{
+		ID:          w.ID,
+		Width:       w.width,
+		Height:      w.height,
+		Generation:  w.generation,
+		Alive:       w.heads,
+		Settled:     w.Period == 1,
+		SettledFrom: w.SettledFrom,
+	}
+}
+
+// Hash returns a position dependent hash of cell states
+func (w *Wireworld) Hash() uint64 {
+	hasher := fnv.New64a()
+	hasher.Write(w.cur)
+	return hasher.Sum64()
+}
+
+// Evolve moves electrons a cell further
+// Circuits without electrons are static, clocked ones periodic but keep running.
+func (w *Wireworld) Evolve() {
+	if w.Period == 0 {
+		w.detectPeriod()
+	}
+	if w.Period == 1 {
+		// Nothing moves anymore.
+		w.generation++
+		return
+	}
+	for y := 0; y < w.height; y++ {
+		for x := 0; x < w.width; x++ {
+			i := y*w.width + x
+			switch state := w.cur[i]; state {
+			case wireHead:
+				w.next[i] = wireTail
+			case wireTail:
+				w.next[i] = wireConductor
+			case wireConductor:
+				w.next[i] = wireConductor
+				if heads := w.headsAround(x, y); heads == 1 || heads == 2 {
+					w.next[i] = wireHead
+				}
+			default:
+				w.next[i] = state
+			}
+		}
+	}
+	w.cur, w.next = w.next, w.cur
+	w.countHeads()
+	w.generation++
+}
+
+// headsAround counts electron heads among the 8 neighbours of a cell
+// Neighbours beyond the edges are looked up through the topology.
+func (w *Wireworld) headsAround(x, y int) int {
+	var heads int
+	for _, offset := range ringOffsets {
+		nx, ny, ok := w.Topology.Resolve(x+offset[0], y+offset[1], w.width, w.height)
+		if ok && w.cur[ny*w.width+nx] == wireHead {
+			heads++
+		}
+	}
+	return heads
+}
+
+// countHeads updates the number of electron heads
+func (w *Wireworld) countHeads() {
+	w.heads = 0
+	for _, state := range w.cur {
+		if state == wireHead {
+			w.heads++
+		}
+	}
+}
+
+// detectPeriod looks for the current generation among recent ones
+func (w *Wireworld) detectPeriod() {
+	if w.detector == nil {
+		w.detector = newPeriodDetector(w.MaxPeriod)
+	}
+	match, ok := w.detector.observe(signature{generation: w.generation, hash: w.Hash()})
+	if !ok {
+		return
+	}
+	w.Period = w.generation - match.generation
+	w.PeriodFrom = match.generation
+	w.SettledFrom = time.Now().UTC()
+	w.detector = nil
+}
+
+// convergence describes what a settled Wireworld converged to
+func (w *Wireworld) convergence() string {
+	switch {
+	case w.Period == 0:
+		return ""
+	case w.Period == 1:
+		return "no electrons"
+	default:
+		return fmt.Sprintf("circuit with period %d", w.Period)
+	}
+}
+
+// Encode returns the JSON representation of the Wireworld
+// Non-empty cells are rendered in colours of their states.
+func (w *Wireworld) Encode() ([]byte, error) {
+	u := wireworldJSON{
+		Kind:       w.Kind(),
+		Colour:     w.Colour,
+		Topology:   w.Topology.Name(),
+		States:     make([][]int, w.height),
+		Matrix:     make([][]bool, w.height),
+		Palette:    wireworldPalette,
+		Colours:    make([][]int, w.height),
+		Width:      w.width,
+		Height:     w.height,
+		ID:         w.ID,
+		Generation: w.generation,
+		Alive:      w.heads,
+		IsStatic:   w.Period == 1,
+		Period:     w.Period,
+		PeriodFrom: w.PeriodFrom,
+		Converged:  w.convergence(),
+	}
+	for y := 0; y < w.height; y++ {
+		u.States[y] = make([]int, w.width)
+		u.Matrix[y] = make([]bool, w.width)
+		u.Colours[y] = make([]int, w.width)
+		for x := 0; x < w.width; x++ {
+			state := w.cur[y*w.width+x]
+			u.States[y][x] = int(state)
+			u.Matrix[y][x] = state != wireEmpty
+			u.Colours[y][x] = int(state) - 1
+		}
+	}
+	return json.Marshal(u)
+}
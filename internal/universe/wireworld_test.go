@@ -0,0 +1,56 @@
+package universe
+
+import "testing"
+
+func TestWireworldEvolution(t *testing.T) {
+	w, err := NewWireworld([][]uint8{{2, 1, 3, 3, 3}}, "#fff", Bounded{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The electron runs right, leaving its tail behind.
+	for _, want := range [][]uint8{{3, 2, 1, 3, 3}, {3, 3, 2, 1, 3}, {3, 3, 3, 2, 1}, {3, 3, 3, 3, 2}, {3, 3, 3, 3, 3}} {
+		w.Evolve()
+		for x, state := range want {
+			if w.cur[x] != state {
+				t.Fatalf("generation %d: states %v, want %v", w.generation, w.cur, want)
+			}
+		}
+	}
+}
+
+func TestWireworldSettles(t *testing.T) {
+	tests := []struct {
+		name        string
+		states      [][]uint8
+		wantPeriod  int
+		wantSettled bool
+	}{
+		// The electron leaves the open wire, nothing moves anymore.
+		{"open wire", [][]uint8{{2, 1, 3, 3, 3}}, 1, true},
+		// An electron circling a loop is a clock, which keeps running. It cuts
+		// corners of the loop across diagonal neighbours.
+		{"clock", [][]uint8{
+			{3, 1, 2, 3},
+			{3, 0, 0, 3},
+			{3, 3, 3, 3},
+		}, 6, false},
+	}
+	for _, tt := range tests {
+		w, err := NewWireworld(tt.states, "#fff", Bounded{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3*tt.wantPeriod+10; i++ {
+			w.Evolve()
+		}
+		if w.Period != tt.wantPeriod {
+			t.Errorf("%s: period %d, want %d", tt.name, w.Period, tt.wantPeriod)
+		}
+		if settled := w.Stats().Settled; settled != tt.wantSettled {
+			t.Errorf("%s: settled %t, want %t", tt.name, settled, tt.wantSettled)
+		}
+		if tt.wantPeriod > 1 && w.heads == 0 {
+			t.Errorf("%s: the clock stopped", tt.name)
+		}
+	}
+}
@@ -33,7 +33,7 @@ func (r *Connection) ReadMessages() {
 		_, _, err := r.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway) {
-				log.Error("Error reading message: %v", err)
+				log.Errorf("Error reading message: %v", err)
 			}
 			log.Debug("Connection closed by the client.")
 			break
@@ -48,6 +48,6 @@ func (r *Connection) ReadMessages() {
 func (r *Connection) SendMessage(data []byte) {
 	err := r.Conn.WriteMessage(websocket.TextMessage, data)
 	if err != nil {
-		log.Error("Error while sending a message. %s. Error: %s", r, err)
+		log.Errorf("Error while sending a message. %s. Error: %s", r, err)
 	}
 }
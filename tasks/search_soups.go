@@ -0,0 +1,114 @@
+package tasks
+
+import (
+	"context"
+	"github.com/ride90/game-of-life/configs"
+	"github.com/ride90/game-of-life/internal/multiverse"
+	"github.com/ride90/game-of-life/internal/search"
+	"github.com/ride90/game-of-life/internal/universe"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// soupColour is the colour of universes of the soup search.
+const soupColour = "#8a8a8a"
+
+// flushInterval is the time between writes of search results.
+const flushInterval = 10 * time.Second
+
+// SearchSoups fills free multiverse slots with random soups, censuses them once
+// they settle & records rare objects until the context is done
+// Results are flushed on the way out.
+func SearchSoups(ctx context.Context, s *search.Search, cfg *configs.Config) {
+	mv := multiverse.GetInstance()
+	rule, err := universe.ParseRule(cfg.Search.Rule)
+	if err != nil {
+		log.Errorf("Soup search stopped: %s", err)
+		return
+	}
+	topology, err := universe.ParseTopology(cfg.Search.Topology)
+	if err != nil {
+		log.Errorf("Soup search stopped: %s", err)
+		return
+	}
+	// Soups are done once they settle or evolve long enough.
+	isDone := func(stats universe.Stats) bool {
+		return stats.Settled || stats.Generation >= cfg.Search.MaxGenerations
+	}
+	// Soups are checked on every tick of the simulation.
+	fpsChanges := mv.WatchFps()
+	ticker := time.NewTicker(tickInterval(mv.Simulation().Fps))
+	flushed := time.Now()
+	defer func() {
+		if err := s.Flush(); err != nil {
+			log.Errorf("Error while persisting soup search results: %s", err)
+		}
+	}()
+	var ids []uint64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fps := <-fpsChanges:
+			ticker.Reset(tickInterval(fps))
+			continue
+		case <-ticker.C:
+		}
+
+		// Census soups which are done, soups removed by clients are forgotten.
+		kept := ids[:0]
+		for _, id := range ids {
+			a, err := mv.TakeUniverse(id, isDone)
+			if err != nil {
+				continue
+			}
+			if a == nil {
+				kept = append(kept, id)
+				continue
+			}
+			u, ok := a.(*universe.Universe)
+			if !ok || u.Soup == nil {
+				log.Errorf("Soup search took universe %d which isn't a soup", id)
+				continue
+			}
+			for _, r := range s.Record(*u.Soup, u.Rule, u.Census()) {
+				log.Infof("Found rare object %s %s in soup with seed %d", r.Code, r.Name, *r.Soup.Seed)
+			}
+		}
+		ids = kept
+		if time.Since(flushed) >= flushInterval {
+			if err := s.Flush(); err != nil {
+				log.Errorf("Error while persisting soup search results: %s", err)
+			}
+			flushed = time.Now()
+		}
+
+		// Fill free slots, the multiverse being full or out of cells stops it.
+		for len(ids) < cfg.Search.Soups {
+			u, err := universe.NewSoup(
+				universe.Soup{
+					Width:    cfg.Search.Width,
+					Height:   cfg.Search.Height,
+					Density:  cfg.Search.Density,
+					Symmetry: cfg.Search.Symmetry,
+				},
+				universe.Options{
+					Colour:    soupColour,
+					Rule:      rule,
+					Topology:  topology,
+					MaxPeriod: cfg.Game.MaxPeriod,
+				},
+			)
+			if err != nil {
+				log.Errorf("Soup search stopped: %s", err)
+				return
+			}
+			id, err := mv.AppendUniverse(u)
+			if err != nil {
+				break
+			}
+			ids = append(ids, id)
+		}
+	}
+}
@@ -10,15 +10,17 @@ import (
 
 func StreamUpdates(wsHub *ws.Hub, cfg *configs.Config) {
 	mv := multiverse.GetInstance()
-	ticker := time.NewTicker(1000 / time.Duration(cfg.Game.Fps) * time.Millisecond)
-	locked := false
+	fpsChanges := mv.WatchFps()
+	ticker := time.NewTicker(tickInterval(mv.Simulation().Fps))
 
-	for _ = range ticker.C {
-		// Check if operation is locked.
-		if locked {
+	for {
+		select {
+		case fps := <-fpsChanges:
+			// Follow changes of the tick rate.
+			ticker.Reset(tickInterval(fps))
 			continue
+		case <-ticker.C:
 		}
-		locked = true
 
 		// Evolve every universe inside multiverse.
 		mv.Evolve(cfg)
@@ -28,8 +30,10 @@ func StreamUpdates(wsHub *ws.Hub, cfg *configs.Config) {
 			log.Errorf("Error while marshaling multiverse into JSON: %s", err)
 		}
 		wsHub.Broadcast(jsonData)
-
-		// Unlock.
-		locked = false
 	}
 }
+
+// tickInterval returns the time between ticks at the given tick rate
+func tickInterval(fps int) time.Duration {
+	return 1000 / time.Duration(fps) * time.Millisecond
+}
@@ -1,7 +1,21 @@
 // Constants
 const UNIVERSE_SIZE = 50;
+const DEFAULT_RULE = "B3/S23";
+const DEFAULT_TOPOLOGY = "torus";
+const DEFAULT_ENGINE = "bitwise";
+const DEFAULT_GRID = "square";
+// Life universes are the default kind, others come with their own fields.
+const DEFAULT_KIND = "life";
+// Default rules of grids, as on the server.
+const GRID_RULES = {square: "B3/S23", hex: "B2/S34", triangular: "B4/S345"};
+// Number of species of multi-colour rules & their default colours, as on the server.
+const SPECIES = {immigration: 2, quadlife: 4};
+const SPECIES_PALETTE = ["#fa725a", "#0099ff", "#a1ff6c", "#FDCB58"];
 const DEAD_CELL_COLOUR = "#2c2c2c";
 const EDITABLE_CELL_COLOUR = "#434343";
+// Opacity lost by each dying state of Generations rules & the lowest one.
+const DYING_CELL_FADE = 0.25;
+const DYING_CELL_MIN_ALPHA = 0.15;
 const API_REQUEST_TIMEOUT = 5000;
 
 // Next 2 lines are pretty damn sad, but I don't care tbh.
@@ -58,10 +72,16 @@ class APIClient {
     }
 
     // Create a new universe
-    createUniverse(colour, cells) {
+    createUniverse(colour, cells, rule, topology, grid, engine, step, colours) {
         this.axios.post(API_URL_BASE + "/universe", {
             colour: colour,
             cells: cells,
+            colours: colours,
+            rule: rule,
+            topology: topology,
+            grid: grid,
+            engine: engine,
+            step: step,
         })
             .then(function (response) {
                 console.log(response);
@@ -71,6 +91,38 @@ class APIClient {
             });
     }
 
+    // Create a universe from a random soup generated by the server
+    randomUniverse(colour, width, height, symmetry, rule, topology, grid, engine, step) {
+        this.axios.post(API_URL_BASE + "/universe/random", {
+            colour: colour,
+            width: width,
+            height: height,
+            symmetry: symmetry,
+            rule: rule,
+            topology: topology,
+            grid: grid,
+            engine: engine,
+            step: step,
+        })
+            .then(function (response) {
+                console.log(response);
+            })
+            .catch(function (error) {
+                alert(error.response.data);
+            });
+    }
+
+    // Delete a universe
+    deleteUniverse(id) {
+        this.axios.delete(API_URL_BASE + "/universe/" + id)
+            .then(function (response) {
+                console.log(response);
+            })
+            .catch(function (error) {
+                alert(error.response.data);
+            });
+    }
+
     // Reset the multiverse
     resetMultiverse() {
         this.axios.post(API_URL_BASE + "/bigbang", {})
@@ -82,9 +134,21 @@ class APIClient {
             });
     }
 
+    // Pause, resume or step the simulation, or a single universe if an ID is given
+    control(action, id) {
+        let url = id ? API_URL_BASE + "/universe/" + id + "/" + action : API_URL_BASE + "/simulation/" + action;
+        this.axios.post(url, {})
+            .then(function (response) {
+                console.log(response);
+            })
+            .catch(function (error) {
+                alert(error.response.data);
+            });
+    }
+
     // Merge universes
-    mergeMultiverse() {
-        this.axios.post(API_URL_BASE + "/merge", {})
+    mergeMultiverse(layout, gap) {
+        this.axios.post(API_URL_BASE + "/merge", {layout: layout, gap: gap})
             .then(function (response) {
                 console.log(response);
             })
@@ -99,6 +163,8 @@ class Multiverse {
     constructor(universes) {
         this.universes = universes || [];
         this.isEditable = true;
+        // Rewinds of universes by ID as of the last update.
+        this.rewinds = {};
         // Get API client and health ping server.
         this.apiClient = new APIClient();
         this.apiClient.health();
@@ -106,7 +172,7 @@ class Multiverse {
 
     // Create a new universe
     createNewUniverse(isEditable) {
-        let universe = new Universe(true, getRandomBrightColor());
+        let universe = new Universe(true, getRandomBrightColor(), null, {});
         universe.isEditable = isEditable;
         this.universes.push(universe);
     }
@@ -118,7 +184,36 @@ class Multiverse {
         // Save universe locally.
         universe.isEditable = false;
         // Create universe on the server.
-        this.apiClient.createUniverse(universe.colour, universe.cells);
+        universe.rule = $("#rule").val() || DEFAULT_RULE;
+        universe.topology = $("#topology").val();
+        universe.grid = $("#grid").val() || DEFAULT_GRID;
+        // The server picks the engine fitting the rule if none is selected.
+        universe.engine = $("#engine").val();
+        universe.step = parseInt($("#step").val(), 10) || 0;
+        // Species of cells matter for multi-colour rules only.
+        let colours = null;
+        if (speciesOf(universe.rule) > 0) {
+            colours = universe.cells.map((row, x) => row.map((alive, y) => alive ? universe.colours[x][y] : -1));
+        }
+        this.apiClient.createUniverse(
+            universe.colour, universe.cells, universe.rule, universe.topology, universe.grid, universe.engine,
+            universe.step, colours
+        );
+    }
+
+    // Replace the most recent universe with a random soup of the same size
+    randomNewUniverse() {
+        let universe = this.universes.pop();
+        this.apiClient.randomUniverse(
+            universe.colour, UNIVERSE_SIZE, UNIVERSE_SIZE, $("#symmetry").val(), $("#rule").val() || DEFAULT_RULE,
+            $("#topology").val(), $("#grid").val() || DEFAULT_GRID, $("#engine").val(),
+            parseInt($("#step").val(), 10) || 0
+        );
+    }
+
+    // Delete a universe on the server
+    deleteUniverse(id) {
+        this.apiClient.deleteUniverse(id);
     }
 
     // Reset the multiverse
@@ -128,7 +223,7 @@ class Multiverse {
 
     // Reset the multiverse
     merge() {
-        this.apiClient.mergeMultiverse();
+        this.apiClient.mergeMultiverse($("#layout").val(), parseInt($("#gap").val(), 10) || 0);
     }
 
     // Remove the most recently created universe
@@ -142,9 +237,15 @@ class Multiverse {
             // Get updates from the server.
             const editableUniverses = this.universes.filter((universe) => universe.isEditable);
             this.universes = [];
+            let rewinds = {};
             for (const data of JSON.parse(event.data)) {
-                this.universes.push(new Universe(false, data.colour, data.cells));
+                let universe = new Universe(false, data.colour, data.cells, data);
+                // Rewound universes are marked until the next update.
+                universe.rewound = universe.id in this.rewinds && this.rewinds[universe.id] !== universe.rewinds;
+                rewinds[universe.id] = universe.rewinds;
+                this.universes.push(universe);
             }
+            this.rewinds = rewinds;
             this.universes = this.universes.concat(editableUniverses);
             // Very "Efficient" re-rendering of all non-editable universes.
             if (onUpdate) {
@@ -200,12 +301,38 @@ let mu = new Multiverse([]);
 
 // Universe class representing an individual universe
 class Universe {
-    constructor(isEditable, colour, cells) {
+    // Info holds the rest of universe fields streamed by the server.
+    constructor(isEditable, colour, cells, info) {
         this.isEditable = isEditable;
         this.colour = colour;
+        info = info || {};
+        this.id = info.id || null;
+        this.kind = info.kind || DEFAULT_KIND;
+        this.rule = info.rule || DEFAULT_RULE;
+        this.topology = info.topology || DEFAULT_TOPOLOGY;
+        this.grid = info.grid || DEFAULT_GRID;
+        this.engine = info.engine || DEFAULT_ENGINE;
+        this.step = info.step || 0;
+        this.generation = info.generation || 0;
+        this.converged = info.converged || "";
+        this.paused = info.paused || false;
+        this.generationsPerTick = info.generations_per_tick || 1;
+        // Generations the universe can be rewound by & the number of its rewinds.
+        this.history = info.history || 0;
+        this.rewinds = info.rewinds || 0;
+        this.rewound = false;
+        // Multi-colour universes come with a palette & palette indices of cells.
+        this.palette = info.palette || null;
+        this.colours = info.colours || null;
+        // Generations universes come with states of cells, 2 & more are dying.
+        // States of other kinds are rendered through the palette.
+        this.states = this.kind === DEFAULT_KIND ? info.states || null : null;
         this.cells = cells || new Array(UNIVERSE_SIZE).fill(false).map(
             () => new Array(UNIVERSE_SIZE).fill(false)
         );
+        if (isEditable && !this.colours) {
+            this.colours = this.cells.map((row) => row.map(() => 0));
+        }
     }
 
     // Render an editable universe
@@ -221,7 +348,7 @@ class Universe {
                 td.attr("y", y);
                 // Highlight cell if it's alive.
                 if (this.cells[x][y]) {
-                    td.css("background", this.colour);
+                    td.css("background", this.editableCellColour(x, y));
                 }
                 td.addClass("editable");
                 // Cell onclick handler.
@@ -246,8 +373,16 @@ class Universe {
         const cellSize = 6;
         const padding = 1;
         let universe = this;
-        const height = (cellSize + padding) * universe.cells.length;
-        const width = (cellSize + padding) * universe.cells[0].length;
+        const step = cellSize + padding;
+        let height = step * universe.cells.length;
+        let width = step * universe.cells[0].length;
+        if (universe.grid === "hex") {
+            // Odd rows are shifted right by half a cell.
+            width += step / 2;
+        } else if (universe.grid === "triangular") {
+            // Triangles are twice as wide as the step between them.
+            width += step;
+        }
         let $canvas = $('<canvas width="' + width + '" height="' + height + '">');
         let canvas = $canvas[0];
         let ctx = canvas.getContext("2d");
@@ -256,16 +391,110 @@ class Universe {
         for (let row = 0; row < universe.cells.length; row++) {
             for (let col = 0; col < universe.cells[row].length; col++) {
                 const cellValue = universe.cells[row][col];
-                // Calculate the position for the current cell
-                const x = col * (cellSize + padding);
-                const y = row * (cellSize + padding);
                 // Set the fill color based on the cell value
-                ctx.fillStyle = cellValue === true ? universe.colour : DEAD_CELL_COLOUR;
+                ctx.fillStyle = cellValue === true ? universe.cellColour(col, row) : DEAD_CELL_COLOUR;
                 // Draw the cell
-                ctx.fillRect(x, y, cellSize, cellSize);
+                universe.drawCell(ctx, col, row, cellSize, padding);
+                // Draw dying cells as fading shades of the universe colour.
+                const state = universe.states ? universe.states[row][col] : 0;
+                if (state > 1) {
+                    ctx.globalAlpha = Math.max(DYING_CELL_MIN_ALPHA, 1 - (state - 1) * DYING_CELL_FADE);
+                    ctx.fillStyle = universe.colour;
+                    universe.drawCell(ctx, col, row, cellSize, padding);
+                    ctx.globalAlpha = 1;
+                }
             }
         }
-        return canvas;
+        return this._wrapWithLabel(canvas);
+    }
+
+    // Draw a cell as a square, a hexagon or a triangle depending on the grid
+    drawCell(ctx, col, row, cellSize, padding) {
+        const step = cellSize + padding;
+        const x = col * step;
+        const y = row * step;
+        if (this.grid === "hex") {
+            // Pointy-top hexagon, odd rows are shifted right by half a cell.
+            const cx = x + step / 2 + (row % 2) * step / 2;
+            const cy = y + step / 2;
+            const r = step / 2;
+            ctx.beginPath();
+            for (let i = 0; i < 6; i++) {
+                const angle = Math.PI / 3 * i + Math.PI / 6;
+                ctx.lineTo(cx + r * Math.cos(angle), cy + r * Math.sin(angle));
+            }
+            ctx.fill();
+            return;
+        }
+        if (this.grid === "triangular") {
+            // Triangles at even col + row point up, others point down.
+            const up = (col + row) % 2 === 0;
+            const top = y + padding / 2;
+            const bottom = y + step - padding / 2;
+            ctx.beginPath();
+            ctx.moveTo(x + padding, up ? bottom : top);
+            ctx.lineTo(x + 2 * step - padding, up ? bottom : top);
+            ctx.lineTo(x + step, up ? top : bottom);
+            ctx.fill();
+            return;
+        }
+        ctx.fillRect(x, y, cellSize, cellSize);
+    }
+
+    // Get the colour of an alive cell
+    cellColour(x, y) {
+        if (this.palette && this.colours) {
+            return this.palette[this.colours[y][x]] || this.colour;
+        }
+        return this.colour;
+    }
+
+    // Wrap a rendered universe with a label describing it
+    _wrapWithLabel(canvas) {
+        let $wrapper = $('<div class="universe-wrapper">');
+        let $label = $('<div class="universe-label">');
+        let label = "#" + this.id + " " + this.rule + " " + this.topology + " " + this.engine;
+        if (this.kind !== DEFAULT_KIND) {
+            label = "#" + this.id + " " + this.kind + " " + this.topology;
+        }
+        if (this.grid !== DEFAULT_GRID) {
+            label += " " + this.grid;
+        }
+        if (this.step > 0) {
+            label += " 2^" + this.step + " gen/tick";
+        }
+        if (this.generationsPerTick > 1) {
+            label += " x" + this.generationsPerTick;
+        }
+        label += " gen " + this.generation;
+        if (this.converged) {
+            label += " " + this.converged;
+        }
+        if (this.paused) {
+            label += " paused";
+        }
+        if (this.rewound) {
+            label += " rewound";
+        }
+        $label.text(label);
+        $label.css("color", this.colour);
+        let $delete = $('<button class="universe-delete" title="Delete universe">x</button>');
+        $delete.on("click", () => mu.deleteUniverse(this.id));
+        $label.append($delete);
+        let $pause = $('<button class="universe-control">').text(this.paused ? "resume" : "pause");
+        $pause.on("click", () => mu.apiClient.control(this.paused ? "resume" : "pause", this.id));
+        $label.append($pause);
+        let $step = $('<button class="universe-control" title="Evolve a generation">step</button>');
+        $step.on("click", () => mu.apiClient.control("step", this.id));
+        $label.append($step);
+        if (this.history > 0) {
+            let $rewind = $('<button class="universe-control" title="Rewind a generation">back</button>');
+            $rewind.on("click", () => mu.apiClient.control("rewind", this.id));
+            $label.append($rewind);
+        }
+        $wrapper.append(canvas);
+        $wrapper.append($label);
+        return $wrapper;
     }
 
     // Render universe
@@ -276,13 +505,28 @@ class Universe {
         return this._renderExisting();
     }
 
+    // Get the colour of an editable cell, species of multi-colour rules have their own
+    editableCellColour(x, y) {
+        if (speciesOf($("#rule").val()) > 0) {
+            return SPECIES_PALETTE[this.colours[x][y]];
+        }
+        return this.colour;
+    }
+
     // Handle cell click event
+    // With a multi-colour rule clicks cycle through species before killing the cell.
     cellOnClickHandler(td, universe) {
         let x = td.attr("x");
         let y = td.attr("y");
-        universe.cells[x][y] = !universe.cells[x][y];
+        let species = speciesOf($("#rule").val());
+        if (universe.cells[x][y] && universe.colours[x][y] + 1 < species) {
+            universe.colours[x][y]++;
+        } else {
+            universe.cells[x][y] = !universe.cells[x][y];
+            universe.colours[x][y] = 0;
+        }
         if (universe.cells[x][y]) {
-            td.css("background", universe.colour);
+            td.css("background", universe.editableCellColour(x, y));
         }
     }
 
@@ -346,6 +590,12 @@ function getRandomBrightColor() {
     return brightColors[newIndex];
 }
 
+// Get the number of species of a multi-colour rule, 0 for other rules
+function speciesOf(rule) {
+    let words = (rule || "").trim().toLowerCase().split(/\s+/);
+    return SPECIES[words[words.length - 1]] || 0;
+}
+
 // Create a new universe and update UI
 function newUniverse() {
     mu.createNewUniverse(true);
@@ -360,17 +610,34 @@ function initApp() {
 
     // Display buttons.
     let newButton = $("#new");
+    let ruleInput = $("#rule");
+    let topologySelect = $("#topology");
+    let gridSelect = $("#grid");
+    let engineSelect = $("#engine");
+    let stepInput = $("#step");
     let saveButton = $("#save");
+    let randomButton = $("#random");
+    let symmetrySelect = $("#symmetry");
     let dropButton = $("#drop");
     let resetButton = $("#reset");
     let mergeButton = $("#merge");
+    let pauseButton = $("#pause");
+    let resumeButton = $("#resume");
+    let stepButton = $("#step-all");
 
     // New universe btn handler.
     newButton.show();
     newButton.on("click", () => {
         newUniverse();
         newButton.hide();
+        ruleInput.show();
+        topologySelect.show();
+        gridSelect.show();
+        engineSelect.show();
+        stepInput.show();
         saveButton.show();
+        randomButton.show();
+        symmetrySelect.show();
         dropButton.show();
     });
 
@@ -379,7 +646,30 @@ function initApp() {
         mu.saveNewUniverse();
         $wizardWrapper.html(mu.renderEditable());
         newButton.show();
+        ruleInput.hide();
+        topologySelect.hide();
+        gridSelect.hide();
+        engineSelect.hide();
+        stepInput.hide();
         saveButton.hide();
+        randomButton.hide();
+        symmetrySelect.hide();
+        dropButton.hide();
+    });
+
+    // Random soup btn handler.
+    randomButton.on("click", () => {
+        mu.randomNewUniverse();
+        $wizardWrapper.html(mu.renderEditable());
+        newButton.show();
+        ruleInput.hide();
+        topologySelect.hide();
+        gridSelect.hide();
+        engineSelect.hide();
+        stepInput.hide();
+        saveButton.hide();
+        randomButton.hide();
+        symmetrySelect.hide();
         dropButton.hide();
     });
 
@@ -388,10 +678,22 @@ function initApp() {
         mu.dropNewUniverse();
         $wizardWrapper.html(mu.renderEditable());
         newButton.show();
+        ruleInput.hide();
+        topologySelect.hide();
+        gridSelect.hide();
+        engineSelect.hide();
+        stepInput.hide();
         saveButton.hide();
+        randomButton.hide();
+        symmetrySelect.hide();
         dropButton.hide();
     });
 
+    // Grids come with their own rules.
+    gridSelect.on("change", () => {
+        ruleInput.val(GRID_RULES[gridSelect.val()] || DEFAULT_RULE);
+    });
+
     // Reset universe btn handler.
     resetButton.on("click", () => {
         if (confirm("Are you sure you want to destroy everything?") == true) {
@@ -406,6 +708,11 @@ function initApp() {
         }
     });
 
+    // Simulation control btn handlers.
+    pauseButton.on("click", () => mu.apiClient.control("pause"));
+    resumeButton.on("click", () => mu.apiClient.control("resume"));
+    stepButton.on("click", () => mu.apiClient.control("step"));
+
     // Get updates and rerender them.
     mu.consumeUpdates(() => $multiverseWrapper.html(mu.renderExisting()));
 }